@@ -3,6 +3,10 @@ package zapwriter
 import (
 	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,12 +22,47 @@ type Config struct {
 	Logger           string `toml:"logger" json:"logger" comment:"handler name, default empty"`
 	File             string `toml:"file" json:"file" comment:"'/path/to/filename', 'stderr', 'stdout', 'empty' (=='stderr'), 'none'"`
 	Level            string `toml:"level" json:"level" comment:"'debug', 'info', 'warn', 'error', 'dpanic', 'panic', and 'fatal'"`
-	Encoding         string `toml:"encoding" json:"encoding" comment:"'json' or 'console'"`
+	Encoding         string `toml:"encoding" json:"encoding" comment:"'mixed', 'json', 'console', 'pretty', 'ecs', 'gelf', 'logfmt' or 'otlp'"`
 	EncodingTime     string `toml:"encoding-time" json:"encoding-time" comment:"'millis', 'nanos', 'epoch', 'iso8601'"`
 	EncodingDuration string `toml:"encoding-duration" json:"encoding-duration" comment:"'seconds', 'nanos', 'string'"`
+	EncodingLevel    string `toml:"encoding-level" json:"encoding-level" comment:"'capital', 'capitalColor', 'lowercase', 'color'"`
+	EncodingCaller   string `toml:"encoding-caller" json:"encoding-caller" comment:"'short', 'full'"`
+	EncodingName     string `toml:"encoding-name" json:"encoding-name" comment:"'full'"`
+	TimeLayout       string `toml:"time-layout" json:"time-layout" comment:"custom time layout (like '2006-01-02 15:04:05'), overrides encoding-time"`
+	MessageKey       string `toml:"message-key" json:"message-key" comment:"default 'message', '-' to omit"`
+	LevelKey         string `toml:"level-key" json:"level-key" comment:"default 'level', '-' to omit"`
+	TimeKey          string `toml:"time-key" json:"time-key" comment:"default 'timestamp', '-' to omit"`
+	NameKey          string `toml:"name-key" json:"name-key" comment:"default 'logger', '-' to omit"`
+	CallerKey        string `toml:"caller-key" json:"caller-key" comment:"default 'caller', '-' to omit"`
+	FunctionKey      string `toml:"function-key" json:"function-key" comment:"default omitted"`
+	StacktraceKey    string `toml:"stacktrace-key" json:"stacktrace-key" comment:"default 'stacktrace', '-' to omit"`
+	LineEnding       string `toml:"line-ending" json:"line-ending" comment:"default '\\n'"`
+	ConsoleSeparator string `toml:"console-separator" json:"console-separator" comment:"field separator of 'console' encoding, default '\\t'"`
 	SampleTick       string `toml:"sample-tick" json:"sample-tick" comment:"passed to time.ParseDuration"`
 	SampleInitial    int    `toml:"sample-initial" json:"sample-initial" comment:"first n messages logged per tick"`
 	SampleThereafter int    `toml:"sample-thereafter" json:"sample-thereafter" comment:"every m-th message logged thereafter per tick"`
+
+	Caller          bool   `toml:"caller" json:"caller" comment:"annotate messages with caller"`
+	CallerSkip      int    `toml:"caller-skip" json:"caller-skip" comment:"skip n additional frames of caller"`
+	StacktraceLevel string `toml:"stacktrace-level" json:"stacktrace-level" comment:"record stacktrace at this level and above, default empty (disabled)"`
+	Development     bool   `toml:"development" json:"development" comment:"DPanic panics"`
+
+	TraceIDKey string `toml:"trace-id-key" json:"trace-id-key" comment:"key of trace id added by zapwriter.Ctx, default 'trace_id', '-' to omit"`
+	SpanIDKey  string `toml:"span-id-key" json:"span-id-key" comment:"key of span id added by zapwriter.Ctx, default 'span_id', '-' to omit"`
+
+	Fields map[string]string `toml:"fields" json:"fields" comment:"static fields added to every message, values can use {hostname}, {pid} and {env:NAME}"`
+
+	Resource map[string]string `toml:"resource" json:"resource" comment:"resource attributes of 'otlp' encoding, values can use templates of fields"`
+
+	Redact RedactConfig `toml:"redact" json:"redact" comment:"masking of secrets before encoding"`
+}
+
+// RedactConfig describes values replaced before encoding. Keys are matched against dotted path of
+// field inside namespaces and objects (e.g. "request.user.password"), "*.password" matches any level
+type RedactConfig struct {
+	Keys     []string `toml:"keys" json:"keys" comment:"glob patterns of field keys like 'token' or '*.password', case insensitive"`
+	Patterns []string `toml:"patterns" json:"patterns" comment:"regexps of secrets in string values and message, like 'Bearer [^ ]+'"`
+	Replace  string   `toml:"replace" json:"replace" comment:"replacement, default '***', 'hash' for sha256 prefix of value"`
 }
 
 func NewConfig() Config {
@@ -38,6 +77,24 @@ func NewConfig() Config {
 
 func (c *Config) Clone() *Config {
 	clone := *c
+	if c.Fields != nil {
+		clone.Fields = make(map[string]string, len(c.Fields))
+		for k, v := range c.Fields {
+			clone.Fields[k] = v
+		}
+	}
+	if c.Resource != nil {
+		clone.Resource = make(map[string]string, len(c.Resource))
+		for k, v := range c.Resource {
+			clone.Resource[k] = v
+		}
+	}
+	if c.Redact.Keys != nil {
+		clone.Redact.Keys = append([]string(nil), c.Redact.Keys...)
+	}
+	if c.Redact.Patterns != nil {
+		clone.Redact.Patterns = append([]string(nil), c.Redact.Patterns...)
+	}
 	return &clone
 }
 
@@ -70,20 +127,33 @@ func (c *Config) encoder() (zapcore.Encoder, zap.AtomicLevel, error) {
 
 	atomicLevel.SetLevel(zapLevel)
 
-	encoding := u.Query().Get("encoding")
-	if encoding == "" {
-		encoding = c.Encoding
+	// DSN query overrides config
+	param := func(key string, value string) string {
+		if v := u.Query().Get(key); v != "" {
+			return v
+		}
+		return value
 	}
 
-	encodingTime := u.Query().Get("encoding-time")
-	if encodingTime == "" {
-		encodingTime = c.EncodingTime
+	// empty key is default, "-" omits key
+	keyParam := func(key string, value string, initial string) string {
+		v := param(key, value)
+		if v == "" {
+			return initial
+		}
+		if v == "-" {
+			return zapcore.OmitKey
+		}
+		return v
 	}
 
-	encodingDuration := u.Query().Get("encoding-duration")
-	if encodingDuration == "" {
-		encodingDuration = c.EncodingDuration
-	}
+	encoding := param("encoding", c.Encoding)
+	encodingTime := param("encoding-time", c.EncodingTime)
+	encodingDuration := param("encoding-duration", c.EncodingDuration)
+	encodingLevel := param("encoding-level", c.EncodingLevel)
+	encodingCaller := param("encoding-caller", c.EncodingCaller)
+	encodingName := param("encoding-name", c.EncodingName)
+	timeLayout := param("time-layout", c.TimeLayout)
 
 	var encoderTime zapcore.TimeEncoder
 
@@ -96,10 +166,18 @@ func (c *Config) encoder() (zapcore.Encoder, zap.AtomicLevel, error) {
 		encoderTime = zapcore.EpochTimeEncoder
 	case "iso8601", "":
 		encoderTime = zapcore.ISO8601TimeEncoder
+	case "rfc3339":
+		encoderTime = zapcore.RFC3339TimeEncoder
+	case "rfc3339nano":
+		encoderTime = zapcore.RFC3339NanoTimeEncoder
 	default:
 		return nil, atomicLevel, fmt.Errorf("unknown time encoding %#v", encodingTime)
 	}
 
+	if timeLayout != "" {
+		encoderTime = zapcore.TimeEncoderOfLayout(timeLayout)
+	}
+
 	var encoderDuration zapcore.DurationEncoder
 	switch strings.ToLower(encodingDuration) {
 	case "seconds", "":
@@ -112,16 +190,63 @@ func (c *Config) encoder() (zapcore.Encoder, zap.AtomicLevel, error) {
 		return nil, atomicLevel, fmt.Errorf("unknown duration encoding %#v", encodingDuration)
 	}
 
+	// logfmt tooling expects lowercase levels
+	if encodingLevel == "" && strings.ToLower(encoding) == "logfmt" {
+		encodingLevel = "lowercase"
+	}
+
+	var encoderLevel zapcore.LevelEncoder
+	switch strings.ToLower(encodingLevel) {
+	case "capital", "":
+		encoderLevel = zapcore.CapitalLevelEncoder
+	case "capitalcolor":
+		encoderLevel = zapcore.CapitalColorLevelEncoder
+	case "lowercase":
+		encoderLevel = zapcore.LowercaseLevelEncoder
+	case "color":
+		encoderLevel = zapcore.LowercaseColorLevelEncoder
+	default:
+		return nil, atomicLevel, fmt.Errorf("unknown level encoding %#v", encodingLevel)
+	}
+
+	var encoderCaller zapcore.CallerEncoder
+	switch strings.ToLower(encodingCaller) {
+	case "short", "":
+		encoderCaller = zapcore.ShortCallerEncoder
+	case "full":
+		encoderCaller = zapcore.FullCallerEncoder
+	default:
+		return nil, atomicLevel, fmt.Errorf("unknown caller encoding %#v", encodingCaller)
+	}
+
+	var encoderName zapcore.NameEncoder
+	switch strings.ToLower(encodingName) {
+	case "full", "":
+		encoderName = zapcore.FullNameEncoder
+	default:
+		return nil, atomicLevel, fmt.Errorf("unknown name encoding %#v", encodingName)
+	}
+
+	messageKey, timeKey := "message", "timestamp"
+	if strings.ToLower(encoding) == "logfmt" {
+		messageKey, timeKey = "msg", "ts"
+	}
+
 	encoderConfig := zapcore.EncoderConfig{
-		MessageKey:     "message",
-		LevelKey:       "level",
-		TimeKey:        "timestamp",
-		NameKey:        "logger",
-		CallerKey:      "caller",
-		StacktraceKey:  "stacktrace",
-		EncodeLevel:    zapcore.CapitalLevelEncoder,
-		EncodeTime:     encoderTime,
-		EncodeDuration: encoderDuration,
+		MessageKey:       keyParam("message-key", c.MessageKey, messageKey),
+		LevelKey:         keyParam("level-key", c.LevelKey, "level"),
+		TimeKey:          keyParam("time-key", c.TimeKey, timeKey),
+		NameKey:          keyParam("name-key", c.NameKey, "logger"),
+		CallerKey:        keyParam("caller-key", c.CallerKey, "caller"),
+		FunctionKey:      keyParam("function-key", c.FunctionKey, zapcore.OmitKey),
+		StacktraceKey:    keyParam("stacktrace-key", c.StacktraceKey, "stacktrace"),
+		LineEnding:       param("line-ending", c.LineEnding),
+		ConsoleSeparator: param("console-separator", c.ConsoleSeparator),
+		EncodeLevel:      encoderLevel,
+		EncodeTime:       encoderTime,
+		EncodeDuration:   encoderDuration,
+		EncodeCaller:     encoderCaller,
+		EncodeName:       encoderName,
 	}
 
 	var encoder zapcore.Encoder
@@ -132,6 +257,38 @@ func (c *Config) encoder() (zapcore.Encoder, zap.AtomicLevel, error) {
 		encoder = zapcore.NewJSONEncoder(encoderConfig)
 	case "console":
 		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	case "ecs":
+		encoder = NewECSEncoder(encoderConfig)
+	case "gelf":
+		encoder = NewGELFEncoder(encoderConfig)
+	case "otlp":
+		resource, err := c.resource()
+		if err != nil {
+			return nil, atomicLevel, err
+		}
+		encoder = NewOTLPEncoder(encoderConfig, resource)
+	case "logfmt":
+		encoder = NewLogfmtEncoder(encoderConfig)
+	case "pretty":
+		var color bool
+		colorMode := param("color", "auto")
+		switch colorMode {
+		case "always":
+			color = true
+		case "never":
+		case "auto":
+			if u.Scheme == "" || u.Scheme == "file" {
+				switch u.Path {
+				case "", "stderr":
+					color = isTerminal(os.Stderr)
+				case "stdout":
+					color = isTerminal(os.Stdout)
+				}
+			}
+		default:
+			return nil, atomicLevel, fmt.Errorf("unknown color %#v", colorMode)
+		}
+		encoder = NewPrettyEncoder(encoderConfig, color)
 	default:
 		return nil, atomicLevel, fmt.Errorf("unknown encoding %#v", encoding)
 	}
@@ -150,6 +307,19 @@ func (c *Config) build(checkOnly bool) (*zap.Logger, error) {
 		return nil, err
 	}
 
+	if _, err := c.fields(); err != nil {
+		return nil, err
+	}
+
+	if _, err := newRedactor(c.Redact); err != nil {
+		return nil, err
+	}
+
+	opts, err := c.options()
+	if err != nil {
+		return nil, err
+	}
+
 	if checkOnly {
 		return nil, nil
 	}
@@ -158,21 +328,183 @@ func (c *Config) build(checkOnly bool) (*zap.Logger, error) {
 		return zap.NewNop(), nil
 	}
 
-	ws, err := New(c.File)
+	var core zapcore.Core
+	if newCore, ok := coreScheme(u.Scheme); ok {
+		core, err = newCore(c.File, encoder, atomicLevel)
+		if err != nil {
+			return nil, err
+		}
+		core, err = c.wrapCore(core, nil)
+	} else {
+		var ws Output
+		ws, err = New(c.File)
+		if err != nil {
+			return nil, err
+		}
+		core, err = c.core(encoder, ws, atomicLevel, nil)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	core, err := c.core(encoder, ws, atomicLevel)
+	return zap.New(core, opts.zapOptions()...), nil
+}
+
+// loggerOptions are options of zap.Logger. Logger with several outputs has union of output options
+type loggerOptions struct {
+	caller      bool
+	callerSkip  int
+	stacktrace  *zapcore.Level // nil - disabled
+	development bool
+}
+
+func (c *Config) options() (loggerOptions, error) {
+	var opts loggerOptions
+
+	u, err := url.Parse(c.File)
 	if err != nil {
-		return nil, err
+		return opts, err
 	}
 
-	return zap.New(core), nil
+	params := DSN(u.Query())
+
+	if opts.caller, err = params.Bool("caller", c.Caller); err != nil {
+		return opts, err
+	}
+	if opts.callerSkip, err = params.Int("caller-skip", c.CallerSkip); err != nil {
+		return opts, err
+	}
+	if opts.development, err = params.Bool("development", c.Development); err != nil {
+		return opts, err
+	}
+
+	stacktraceLevel, err := params.String("stacktrace-level", c.StacktraceLevel)
+	if err != nil {
+		return opts, err
+	}
+	if stacktraceLevel != "" {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(stacktraceLevel)); err != nil {
+			return opts, err
+		}
+		opts.stacktrace = &level
+	}
+
+	return opts, nil
 }
 
-func (c *Config) core(encoder zapcore.Encoder, ws zapcore.WriteSyncer, atomicLevel zap.AtomicLevel) (zapcore.Core, error) {
-	core := zapcore.NewCore(encoder, ws, atomicLevel)
+// traceKeys returns keys of trace fields from context, empty key is omitted
+func (c *Config) traceKeys() (string, string, error) {
+	u, err := url.Parse(c.File)
+	if err != nil {
+		return "", "", err
+	}
+
+	params := DSN(u.Query())
+
+	key := func(name string, value string, initial string) (string, error) {
+		v, err := params.String(name, value)
+		if err != nil {
+			return "", err
+		}
+		switch v {
+		case "":
+			return initial, nil
+		case "-":
+			return "", nil
+		}
+		return v, nil
+	}
+
+	traceIDKey, err := key("trace-id-key", c.TraceIDKey, defaultTraceIDKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	spanIDKey, err := key("span-id-key", c.SpanIDKey, defaultSpanIDKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	return traceIDKey, spanIDKey, nil
+}
+
+func (o loggerOptions) merge(other loggerOptions) loggerOptions {
+	res := o
+	res.caller = o.caller || other.caller
+	res.development = o.development || other.development
+	if other.callerSkip > res.callerSkip {
+		res.callerSkip = other.callerSkip
+	}
+	if other.stacktrace != nil && (res.stacktrace == nil || *other.stacktrace < *res.stacktrace) {
+		res.stacktrace = other.stacktrace
+	}
+	return res
+}
+
+func (o loggerOptions) zapOptions() []zap.Option {
+	var res []zap.Option
+	if o.caller {
+		res = append(res, zap.AddCaller(), zap.AddCallerSkip(o.callerSkip))
+	}
+	if o.stacktrace != nil {
+		res = append(res, zap.AddStacktrace(*o.stacktrace))
+	}
+	if o.development {
+		res = append(res, zap.Development())
+	}
+	return res
+}
+
+func (c *Config) core(encoder zapcore.Encoder, ws zapcore.WriteSyncer, atomicLevel zap.AtomicLevel, loggerOpts *loggerOptions) (zapcore.Core, error) {
+	if o, ok := ws.(*output); ok && o.wantsEntries() {
+		return c.wrapCore(&entryCore{LevelEnabler: atomicLevel, enc: encoder, out: o}, loggerOpts)
+	}
+	return c.wrapCore(zapcore.NewCore(encoder, ws, atomicLevel), loggerOpts)
+}
+
+// wrapCore adds redaction, sampling and static fields to core of output. If logger options (union of all logger outputs)
+// are wider than output options, caller and stacktrace not requested by this output are removed from entries
+func (c *Config) wrapCore(core zapcore.Core, loggerOpts *loggerOptions) (zapcore.Core, error) {
+
+	if len(c.Redact.Keys) > 0 || len(c.Redact.Patterns) > 0 {
+		r, err := newRedactor(c.Redact)
+		if err != nil {
+			return nil, err
+		}
+		core = &redactCore{Core: core, redactor: r}
+	}
+
+	traceIDKey, spanIDKey, err := c.traceKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	if traceIDKey != defaultTraceIDKey || spanIDKey != defaultSpanIDKey {
+		core = &traceKeysCore{
+			Core:       core,
+			traceIDKey: traceIDKey,
+			spanIDKey:  spanIDKey,
+		}
+	}
+
+	if loggerOpts != nil {
+		opts, err := c.options()
+		if err != nil {
+			return nil, err
+		}
+
+		stripCaller := loggerOpts.caller && !opts.caller
+		stripStack := loggerOpts.stacktrace != nil && (opts.stacktrace == nil || *opts.stacktrace > *loggerOpts.stacktrace)
+
+		if stripCaller || stripStack {
+			core = &entryFilterCore{
+				Core:        core,
+				stripCaller: stripCaller,
+				stacktrace:  opts.stacktrace,
+			}
+		}
+	}
 
 	if c.SampleTick != "" {
 		if c.SampleThereafter == 0 {
@@ -187,5 +519,110 @@ func (c *Config) core(encoder zapcore.Encoder, ws zapcore.WriteSyncer, atomicLev
 		core = zapcore.NewSampler(core, d, c.SampleInitial, c.SampleThereafter)
 	}
 
+	fields, err := c.fields()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) > 0 {
+		core = core.With(fields)
+	}
+
 	return core, nil
 }
+
+// fields returns static fields with expanded templates, sorted by key
+func (c *Config) fields() ([]zapcore.Field, error) {
+	keys := make([]string, 0, len(c.Fields))
+	for k := range c.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]zapcore.Field, 0, len(keys))
+	for _, k := range keys {
+		v, err := expandTemplate(c.Fields[k])
+		if err != nil {
+			return nil, fmt.Errorf("field %#v: %s", k, err.Error())
+		}
+		fields = append(fields, zap.String(k, v))
+	}
+
+	return fields, nil
+}
+
+// resource returns resource attributes with expanded templates. service.name is required by OpenTelemetry,
+// default is "unknown_service:<executable>"
+func (c *Config) resource() (map[string]string, error) {
+	resource := make(map[string]string, len(c.Resource)+1)
+	for k, v := range c.Resource {
+		value, err := expandTemplate(v)
+		if err != nil {
+			return nil, fmt.Errorf("resource %#v: %s", k, err.Error())
+		}
+		resource[k] = value
+	}
+
+	if _, ok := resource["service.name"]; !ok {
+		resource["service.name"] = "unknown_service:" + filepath.Base(os.Args[0])
+	}
+
+	return resource, nil
+}
+
+// expandTemplate replaces {hostname}, {pid} and {env:NAME} in s. Other braces are kept as is,
+// so values like JSON strings need no escaping
+func expandTemplate(s string) (string, error) {
+	var b strings.Builder
+
+	for {
+		start := strings.Index(s, "{")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+
+		b.WriteString(s[:start])
+		s = s[start:]
+
+		end := strings.Index(s, "}")
+		if end < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+
+		name := s[1:end]
+		switch {
+		case name == "hostname":
+			hostname, err := os.Hostname()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(hostname)
+		case name == "pid":
+			b.WriteString(strconv.Itoa(os.Getpid()))
+		case strings.HasPrefix(name, "env:") && isEnvName(name[4:]):
+			b.WriteString(os.Getenv(name[4:]))
+		default:
+			// not a template
+			b.WriteByte('{')
+			s = s[1:]
+			continue
+		}
+
+		s = s[end+1:]
+	}
+}
+
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
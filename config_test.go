@@ -0,0 +1,89 @@
+package zapwriter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func testConfigLogger(t *testing.T, cfg Config) (*zap.Logger, *testBuffer) {
+	encoder, atomicLevel, err := cfg.encoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &testBuffer{}
+	core, err := cfg.core(encoder, buf, atomicLevel, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return zap.New(core), buf
+}
+
+func TestConfigFields(t *testing.T) {
+	os.Setenv("ZAPWRITER_TEST_DC", "dc1")
+	defer os.Unsetenv("ZAPWRITER_TEST_DC")
+
+	hostname, _ := os.Hostname()
+
+	cfg := NewConfig()
+	cfg.Fields = map[string]string{
+		"service": "app",
+		"host":    "{hostname}",
+		"dc":      "{env:ZAPWRITER_TEST_DC}",
+		"pid":     "pid-{pid}",
+	}
+
+	logger, buf := testConfigLogger(t, cfg)
+	logger.Info("message text", zap.String("key", "value"))
+
+	expected := fmt.Sprintf(`message text {"dc": "dc1", "host": "%s", "pid": "pid-%d", "service": "app", "key": "value"}`, hostname, os.Getpid())
+	if out := buf.String(); !strings.Contains(out, expected) {
+		t.Fatal(out)
+	}
+
+	// only known templates are expanded
+	cfg.Fields = map[string]string{"json": `{"pid":{pid},"env":"{env:}","x":{}}`}
+
+	logger, buf = testConfigLogger(t, cfg)
+	logger.Info("message text")
+
+	expected = fmt.Sprintf(`{"json": "{\"pid\":%d,\"env\":\"{env:}\",\"x\":{}}"}`, os.Getpid())
+	if out := buf.String(); !strings.Contains(out, expected) {
+		t.Fatal(out)
+	}
+}
+
+func TestConfigEncoderKeys(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Encoding = "json"
+	cfg.MessageKey = "msg"
+	cfg.TimeKey = "-"
+	cfg.LevelKey = "lvl"
+	cfg.EncodingLevel = "lowercase"
+
+	logger, buf := testConfigLogger(t, cfg)
+	logger.Info("message text")
+
+	if out := buf.Capture(); out != `{"lvl":"info","msg":"message text"}`+"\n" {
+		t.Fatal(out)
+	}
+
+	// DSN overrides config
+	cfg.File = "stderr?message-key=text&time-key=ts&time-layout=2006"
+	logger, buf = testConfigLogger(t, cfg)
+	logger.Info("message text")
+
+	if out := buf.Capture(); !strings.HasPrefix(out, `{"lvl":"info","ts":"20`) || !strings.Contains(out, `"text":"message text"`) {
+		t.Fatal(out)
+	}
+
+	cfg.EncodingLevel = "unknown"
+	if err := cfg.Check(); err == nil {
+		t.Fatal("error expected")
+	}
+}
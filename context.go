@@ -0,0 +1,183 @@
+package zapwriter
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TraceExtractor returns trace and span ids stored in ctx, empty strings if there are none
+type TraceExtractor func(ctx context.Context) (traceID, spanID string)
+
+var (
+	_traceExtractorsMu sync.RWMutex
+	_traceExtractors   = []TraceExtractor{W3CTraceExtractor}
+)
+
+// RegisterTraceExtractor adds extractor of trace ids, e.g. from OpenTelemetry span context.
+// Extractors are tried in order of registration, built-in W3CTraceExtractor is first
+func RegisterTraceExtractor(fn TraceExtractor) {
+	_traceExtractorsMu.Lock()
+	_traceExtractors = append(_traceExtractors, fn)
+	_traceExtractorsMu.Unlock()
+}
+
+type traceparentKey struct{}
+
+// WithTraceparent returns context with W3C traceparent header value, used by W3CTraceExtractor
+func WithTraceparent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceparentKey{}, traceparent)
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// W3CTraceExtractor extracts ids from traceparent added by WithTraceparent:
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func W3CTraceExtractor(ctx context.Context) (traceID, spanID string) {
+	traceparent, _ := ctx.Value(traceparentKey{}).(string)
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) < 2 {
+		return "", ""
+	}
+	// future versions can append fields
+	if parts[0] == "00" && len(parts) != 4 {
+		return "", ""
+	}
+	if !isHex(parts[0]) || !isHex(parts[1]) || !isHex(parts[2]) || !isHex(parts[3][:2]) {
+		return "", ""
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", ""
+	}
+	return parts[1], parts[2]
+}
+
+// traceField marks fields from context. They have default keys and are renamed
+// to trace-id-key and span-id-key of every output by traceKeysCore
+type traceField int
+
+const (
+	traceIDField traceField = iota
+	spanIDField
+)
+
+const (
+	defaultTraceIDKey = "trace_id"
+	defaultSpanIDKey  = "span_id"
+)
+
+// TraceFields returns trace_id and span_id fields of ctx, nil if ctx has no trace
+func TraceFields(ctx context.Context) []zap.Field {
+	_traceExtractorsMu.RLock()
+	defer _traceExtractorsMu.RUnlock()
+
+	for _, extract := range _traceExtractors {
+		traceID, spanID := extract(ctx)
+		if traceID == "" {
+			continue
+		}
+
+		fields := []zap.Field{{Key: defaultTraceIDKey, Type: zapcore.StringType, String: traceID, Interface: traceIDField}}
+		if spanID != "" {
+			fields = append(fields, zap.Field{Key: defaultSpanIDKey, Type: zapcore.StringType, String: spanID, Interface: spanIDField})
+		}
+		return fields
+	}
+
+	return nil
+}
+
+// WithContext returns logger with trace fields of ctx
+func WithContext(logger *zap.Logger, ctx context.Context) *zap.Logger {
+	if fields := TraceFields(ctx); len(fields) > 0 {
+		return logger.With(fields...)
+	}
+	return logger
+}
+
+// ContextLoggers returns loggers with trace fields of context
+type ContextLoggers struct {
+	ctx context.Context
+}
+
+// Ctx is shortcut for loggers with trace fields: zapwriter.Ctx(ctx).Logger("access")
+func Ctx(ctx context.Context) ContextLoggers {
+	return ContextLoggers{ctx: ctx}
+}
+
+func (c ContextLoggers) Default() *zap.Logger {
+	return WithContext(Default(), c.ctx)
+}
+
+func (c ContextLoggers) Logger(logger string) *zap.Logger {
+	return WithContext(Logger(logger), c.ctx)
+}
+
+// traceKeysCore renames trace fields from context to keys of output
+type traceKeysCore struct {
+	zapcore.Core
+	traceIDKey string // empty - omit
+	spanIDKey  string // empty - omit
+}
+
+func (c *traceKeysCore) rename(fields []zapcore.Field) []zapcore.Field {
+	var res []zapcore.Field
+	for i, f := range fields {
+		kind, ok := f.Interface.(traceField)
+		if !ok || f.Type != zapcore.StringType {
+			if res != nil {
+				res = append(res, f)
+			}
+			continue
+		}
+
+		if res == nil {
+			res = make([]zapcore.Field, i, len(fields))
+			copy(res, fields[:i])
+		}
+
+		if kind == traceIDField {
+			f.Key = c.traceIDKey
+		} else {
+			f.Key = c.spanIDKey
+		}
+		if f.Key != "" {
+			res = append(res, f)
+		}
+	}
+
+	if res == nil {
+		return fields
+	}
+	return res
+}
+
+func (c *traceKeysCore) With(fields []zapcore.Field) zapcore.Core {
+	return &traceKeysCore{
+		Core:       c.Core.With(c.rename(fields)),
+		traceIDKey: c.traceIDKey,
+		spanIDKey:  c.spanIDKey,
+	}
+}
+
+func (c *traceKeysCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *traceKeysCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.rename(fields))
+}
@@ -0,0 +1,88 @@
+package zapwriter
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type testTraceKey struct{}
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestW3CTraceExtractor(t *testing.T) {
+	table := []struct {
+		traceparent string
+		traceID     string
+		spanID      string
+	}{
+		{testTraceparent, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", "", ""},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", "", ""},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", "", ""},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "", ""},
+		{"", "", ""},
+	}
+
+	for _, c := range table {
+		traceID, spanID := W3CTraceExtractor(WithTraceparent(context.Background(), c.traceparent))
+		if traceID != c.traceID || spanID != c.spanID {
+			t.Fatalf("%#v: %#v %#v", c.traceparent, traceID, spanID)
+		}
+	}
+}
+
+func TestContextTraceKeys(t *testing.T) {
+	ctx := WithTraceparent(context.Background(), testTraceparent)
+
+	cfg := NewConfig()
+	cfg.Encoding = "json"
+	cfg.TimeKey = "-"
+
+	logger, buf := testConfigLogger(t, cfg)
+	WithContext(logger, ctx).Info("default keys")
+
+	if out := buf.Capture(); !strings.Contains(out, `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"`) {
+		t.Fatal(out)
+	}
+
+	cfg.TraceIDKey = "traceId"
+	cfg.SpanIDKey = "-"
+
+	logger, buf = testConfigLogger(t, cfg)
+	WithContext(logger, ctx).Info("custom keys")
+
+	if out := buf.Capture(); !strings.Contains(out, `"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"}`) || strings.Contains(out, "00f067aa0ba902b7") {
+		t.Fatal(out)
+	}
+
+	// call-site fields are renamed too
+	logger.Info("fields", TraceFields(ctx)...)
+	if out := buf.Capture(); !strings.Contains(out, `"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"}`) {
+		t.Fatal(out)
+	}
+
+	// context without trace
+	if l := WithContext(logger, context.Background()); l != logger {
+		t.Fatal("logger without trace fields expected")
+	}
+}
+
+func TestCtxLogger(t *testing.T) {
+	defer testWithConfig(NewConfig())()
+
+	RegisterTraceExtractor(func(ctx context.Context) (string, string) {
+		if id, ok := ctx.Value(testTraceKey{}).(string); ok {
+			return id, ""
+		}
+		return "", ""
+	})
+
+	ctx := context.WithValue(context.Background(), testTraceKey{}, "custom-id")
+	Ctx(ctx).Logger("access").Info("message text")
+
+	if out := TestCapture(); !strings.Contains(out, `{"trace_id": "custom-id"}`) {
+		t.Fatal(out)
+	}
+}
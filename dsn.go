@@ -16,12 +16,72 @@ func DSN(values url.Values) *DsnObj {
 	return &DsnObj{values}
 }
 
+// Duration parses time.ParseDuration values with additional days prefix: "7d", "1d12h"
 func (dsn *DsnObj) Duration(key string, initial string) (time.Duration, error) {
 	s := dsn.Get(key)
 	if s == "" {
-		return time.ParseDuration(initial)
+		return parseDuration(initial)
 	}
-	return time.ParseDuration(s)
+	return parseDuration(s)
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	i := strings.Index(s, "d")
+	if i < 0 {
+		return time.ParseDuration(s)
+	}
+
+	days, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %#v", s)
+	}
+
+	d := time.Duration(days) * 24 * time.Hour
+	if i == len(s)-1 {
+		return d, nil
+	}
+
+	rest, err := time.ParseDuration(s[i+1:])
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %#v", s)
+	}
+	return d + rest, nil
+}
+
+// Size parses values like "512", "64KB", "100MB" or "1G". Units are
+// powers of 1024
+func (dsn *DsnObj) Size(key string, initial string) (int64, error) {
+	s := dsn.Get(key)
+	if s == "" {
+		return parseSize(initial)
+	}
+	return parseSize(s)
+}
+
+func parseSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	v = strings.TrimSuffix(v, "B")
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(v, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(v, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(v, "G"):
+		multiplier = 1 << 30
+	case strings.HasSuffix(v, "T"):
+		multiplier = 1 << 40
+	}
+	if multiplier > 1 {
+		v = v[:len(v)-1]
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %#v", s)
+	}
+	return n * multiplier, nil
 }
 
 func (dsn *DsnObj) Int(key string, initial int) (int, error) {
@@ -0,0 +1,190 @@
+package zapwriter
+
+import (
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const ecsVersion = "1.6.0"
+
+// ecsEncoder writes json with Elastic Common Schema field names.
+// Top-level "error" and "errorVerbose" keys added by zap.Error are merged
+// into {"error": {"message": ..., "stack_trace": ...}}. Stacktrace of entry is error.stack_trace too,
+// unless error already has own stack trace
+type ecsEncoder struct {
+	*jsonEncoder
+	err      *ecsError // not written yet error, zap.Error adds verbose message with separate call
+	errStack bool      // error with stack_trace is written
+}
+
+type ecsError struct {
+	message    string
+	stackTrace string
+}
+
+func NewECSEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &ecsEncoder{jsonEncoder: newJSONEncoder(cfg, false)}
+}
+
+// flushError writes pending error. Must be called before any other key is added
+func (enc *ecsEncoder) flushError() {
+	if enc.err == nil {
+		return
+	}
+	e := enc.err
+	enc.err = nil
+
+	enc.addKey("error")
+	enc.buf.AppendByte('{')
+	enc.jsonEncoder.AddString("message", e.message)
+	if e.stackTrace != "" {
+		enc.jsonEncoder.AddString("stack_trace", e.stackTrace)
+		enc.errStack = true
+	}
+	enc.buf.AppendByte('}')
+}
+
+func (enc *ecsEncoder) AddString(key, val string) {
+	if enc.openNamespaces == 0 {
+		if key == "errorVerbose" && enc.err != nil {
+			enc.err.stackTrace = val
+			return
+		}
+		if key == "error" {
+			enc.flushError()
+			enc.err = &ecsError{message: val}
+			return
+		}
+	}
+	enc.flushError()
+	enc.jsonEncoder.AddString(key, val)
+}
+
+func (enc *ecsEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	enc.flushError()
+	return enc.jsonEncoder.AddArray(key, arr)
+}
+
+func (enc *ecsEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	enc.flushError()
+	return enc.jsonEncoder.AddObject(key, obj)
+}
+
+func (enc *ecsEncoder) AddReflected(key string, obj interface{}) error {
+	enc.flushError()
+	return enc.jsonEncoder.AddReflected(key, obj)
+}
+
+func (enc *ecsEncoder) OpenNamespace(key string) {
+	enc.flushError()
+	enc.jsonEncoder.OpenNamespace(key)
+}
+
+func (enc *ecsEncoder) AddBinary(k string, v []byte) {
+	enc.flushError()
+	enc.jsonEncoder.AddBinary(k, v)
+}
+func (enc *ecsEncoder) AddByteString(k string, v []byte) {
+	enc.flushError()
+	enc.jsonEncoder.AddByteString(k, v)
+}
+func (enc *ecsEncoder) AddBool(k string, v bool) { enc.flushError(); enc.jsonEncoder.AddBool(k, v) }
+func (enc *ecsEncoder) AddComplex128(k string, v complex128) {
+	enc.flushError()
+	enc.jsonEncoder.AddComplex128(k, v)
+}
+func (enc *ecsEncoder) AddComplex64(k string, v complex64) { enc.AddComplex128(k, complex128(v)) }
+func (enc *ecsEncoder) AddDuration(k string, v time.Duration) {
+	enc.flushError()
+	enc.jsonEncoder.AddDuration(k, v)
+}
+func (enc *ecsEncoder) AddFloat64(k string, v float64) {
+	enc.flushError()
+	enc.jsonEncoder.AddFloat64(k, v)
+}
+func (enc *ecsEncoder) AddFloat32(k string, v float32) { enc.AddFloat64(k, float64(v)) }
+func (enc *ecsEncoder) AddInt64(k string, v int64)     { enc.flushError(); enc.jsonEncoder.AddInt64(k, v) }
+func (enc *ecsEncoder) AddInt(k string, v int)         { enc.AddInt64(k, int64(v)) }
+func (enc *ecsEncoder) AddInt32(k string, v int32)     { enc.AddInt64(k, int64(v)) }
+func (enc *ecsEncoder) AddInt16(k string, v int16)     { enc.AddInt64(k, int64(v)) }
+func (enc *ecsEncoder) AddInt8(k string, v int8)       { enc.AddInt64(k, int64(v)) }
+func (enc *ecsEncoder) AddTime(k string, v time.Time) {
+	enc.flushError()
+	enc.jsonEncoder.AddTime(k, v)
+}
+func (enc *ecsEncoder) AddUint64(k string, v uint64) {
+	enc.flushError()
+	enc.jsonEncoder.AddUint64(k, v)
+}
+func (enc *ecsEncoder) AddUint(k string, v uint)       { enc.AddUint64(k, uint64(v)) }
+func (enc *ecsEncoder) AddUint32(k string, v uint32)   { enc.AddUint64(k, uint64(v)) }
+func (enc *ecsEncoder) AddUint16(k string, v uint16)   { enc.AddUint64(k, uint64(v)) }
+func (enc *ecsEncoder) AddUint8(k string, v uint8)     { enc.AddUint64(k, uint64(v)) }
+func (enc *ecsEncoder) AddUintptr(k string, v uintptr) { enc.AddUint64(k, uint64(v)) }
+
+func (enc *ecsEncoder) Clone() zapcore.Encoder {
+	clone := enc.clone()
+	clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *ecsEncoder) clone() *ecsEncoder {
+	clone := &ecsEncoder{jsonEncoder: enc.jsonEncoder.clone(), errStack: enc.errStack}
+	if enc.err != nil {
+		e := *enc.err
+		clone.err = &e
+	}
+	return clone
+}
+
+func (enc *ecsEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+	final.buf.AppendByte('{')
+
+	final.jsonEncoder.AddString("@timestamp", ent.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+	final.jsonEncoder.AddString("log.level", ent.Level.String())
+	final.jsonEncoder.AddString("message", ent.Message)
+	final.jsonEncoder.AddString("ecs.version", ecsVersion)
+
+	if ent.LoggerName != "" {
+		final.jsonEncoder.AddString("log.logger", ent.LoggerName)
+	}
+
+	if ent.Caller.Defined {
+		file := ent.Caller.TrimmedPath()
+		if i := strings.LastIndexByte(file, ':'); i >= 0 {
+			file = file[:i]
+		}
+		final.addKey("log.origin")
+		final.buf.AppendByte('{')
+		final.jsonEncoder.AddString("file.name", file)
+		final.jsonEncoder.AddInt64("file.line", int64(ent.Caller.Line))
+		if ent.Caller.Function != "" {
+			final.jsonEncoder.AddString("function", ent.Caller.Function)
+		}
+		final.buf.AppendByte('}')
+	}
+
+	if enc.buf.Len() > 0 {
+		final.addElementSeparator()
+		final.buf.Write(enc.buf.Bytes())
+	}
+	addFields(final, fields)
+	if ent.Stack != "" && final.err != nil && final.err.stackTrace == "" {
+		final.err.stackTrace = ent.Stack
+	}
+	final.flushError()
+	final.closeOpenNamespaces()
+	if ent.Stack != "" && !final.errStack {
+		final.jsonEncoder.AddString("error.stack_trace", ent.Stack)
+	}
+	final.buf.AppendByte('}')
+	final.buf.AppendByte('\n')
+
+	ret := final.buf
+	putJSONEncoder(final.jsonEncoder)
+	return ret, nil
+}
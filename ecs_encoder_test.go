@@ -0,0 +1,98 @@
+package zapwriter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type testVerboseError struct{}
+
+func (testVerboseError) Error() string { return "fail" }
+
+func (e testVerboseError) Format(s fmt.State, verb rune) {
+	if s.Flag('+') {
+		fmt.Fprint(s, "fail\nstack")
+		return
+	}
+	fmt.Fprint(s, "fail")
+}
+
+func TestECSEncoder(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Encoding = "ecs"
+
+	logger, buf := testConfigLogger(t, cfg)
+	logger = logger.WithOptions(zap.AddCaller())
+	logger.Named("access").With(zap.Error(testVerboseError{})).Info("message text",
+		zap.String("key", "value"),
+		zap.Namespace("request"),
+		zap.String("error", "not top-level"),
+	)
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &msg); err != nil {
+		t.Fatal(err, buf.String())
+	}
+
+	expected := map[string]interface{}{
+		"log.level":   "info",
+		"message":     "message text",
+		"ecs.version": ecsVersion,
+		"log.logger":  "access",
+		"key":         "value",
+	}
+	for k, v := range expected {
+		if msg[k] != v {
+			t.Fatalf("%s: %s", k, buf.String())
+		}
+	}
+
+	if _, ok := msg["@timestamp"].(string); !ok {
+		t.Fatal(buf.String())
+	}
+
+	origin, _ := msg["log.origin"].(map[string]interface{})
+	if file, _ := origin["file.name"].(string); !strings.HasSuffix(file, "/ecs_encoder_test.go") {
+		t.Fatal(buf.String())
+	}
+
+	errObj, _ := msg["error"].(map[string]interface{})
+	if errObj["message"] != "fail" || errObj["stack_trace"] != "fail\nstack" {
+		t.Fatal(buf.String())
+	}
+
+	request, _ := msg["request"].(map[string]interface{})
+	if request["error"] != "not top-level" {
+		t.Fatal(buf.String())
+	}
+}
+
+func TestECSEncoderStacktrace(t *testing.T) {
+	enc := NewECSEncoder(zap.NewProductionEncoderConfig())
+	ent := zapcore.Entry{Level: zapcore.ErrorLevel, Message: "message text", Stack: "entry stack"}
+
+	table := []struct {
+		fields   []zapcore.Field
+		expected string
+	}{
+		{nil, `"error.stack_trace":"entry stack"`},
+		{[]zapcore.Field{zap.Error(errors.New("fail"))}, `"error":{"message":"fail","stack_trace":"entry stack"}`},
+		{[]zapcore.Field{zap.Error(testVerboseError{})}, `"error":{"message":"fail","stack_trace":"fail\nstack"}}`},
+	}
+
+	for _, test := range table {
+		out, err := enc.EncodeEntry(ent, test.fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), test.expected) || strings.Count(out.String(), "stack_trace") != 1 {
+			t.Fatal(out.String())
+		}
+	}
+}
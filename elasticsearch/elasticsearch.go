@@ -0,0 +1,213 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lomik/zapwriter"
+	"github.com/lomik/zapwriter/httpbatch"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	zapwriter.RegisterScheme("elasticsearch", New)
+	zapwriter.RegisterScheme("opensearch", New)
+}
+
+// ElasticsearchOutput writes JSON encoded entries with _bulk API. Use it with "json" encoding.
+//
+// DSN example:
+//
+//	elasticsearch://es:9200/?index=logs-app-%25Y.%25m.%25d&tls=true&error_logger=es_error
+//
+// Params:
+//
+//	index=zapwriter-%Y.%m.%d  index name, strftime-style pattern of entry time (escape % as %25)
+//	op=index                  bulk operation: "index" or "create" (data streams)
+//	tls=true                  use https
+//
+// Rejected documents are logged to error_logger. Batching and retry params are described in httpbatch.ParseOptions
+type ElasticsearchOutput struct {
+	*httpbatch.Batcher
+	index       string
+	op          string
+	errorLogger string
+}
+
+type document struct {
+	index string
+	body  []byte
+}
+
+// param returns query param. Values with unescaped % (like "logs-%Y") are returned as is
+func param(u *url.URL, key string) string {
+	if v := u.Query().Get(key); v != "" {
+		return v
+	}
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if strings.HasPrefix(pair, key+"=") {
+			return strings.TrimPrefix(pair, key+"=")
+		}
+	}
+	return ""
+}
+
+func New(path string) (zapwriter.Output, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	params := zapwriter.DSN(u.Query())
+
+	tls, err := params.Bool("tls", false)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/_bulk"
+	}
+
+	index := param(u, "index")
+	if index == "" {
+		index = "zapwriter-%Y.%m.%d"
+	}
+
+	// drop index with unescaped % from query
+	raw := make([]string, 0)
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if !strings.HasPrefix(pair, "index=") {
+			raw = append(raw, pair)
+		}
+	}
+	u.RawQuery = strings.Join(raw, "&")
+
+	opts, err := httpbatch.ParseOptions(u, scheme, "tls", "op")
+	if err != nil {
+		return nil, err
+	}
+	if opts.ContentType == "" {
+		opts.ContentType = "application/x-ndjson"
+	}
+
+	op, err := params.String("op", "index")
+	if err != nil {
+		return nil, err
+	}
+	if op != "index" && op != "create" {
+		return nil, fmt.Errorf("unknown op %#v", op)
+	}
+
+	r := &ElasticsearchOutput{
+		index:       index,
+		op:          op,
+		errorLogger: opts.ErrorLogger,
+	}
+	r.Batcher = httpbatch.NewBatcher(opts, r.encode, r.check)
+
+	return r, nil
+}
+
+func (r *ElasticsearchOutput) add(t time.Time, p []byte) (int, error) {
+	// data race fix
+	body := bytes.TrimRight(p, "\n")
+	m := make([]byte, len(body))
+	copy(m, body)
+
+	if err := r.Add(&document{index: zapwriter.Strftime(r.index, t), body: m}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (r *ElasticsearchOutput) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) (int, error) {
+	t := ent.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return r.add(t, p)
+}
+
+func (r *ElasticsearchOutput) Write(p []byte) (int, error) {
+	return r.add(time.Now(), p)
+}
+
+func (r *ElasticsearchOutput) encode(items []interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, item := range items {
+		doc := item.(*document)
+
+		action, err := json.Marshal(map[string]map[string]string{r.op: {"_index": doc.index}})
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(action)
+		buf.WriteByte('\n')
+		buf.Write(doc.body)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkItemResponse `json:"items"`
+}
+
+type bulkItemResponse struct {
+	Index  string          `json:"_index"`
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// check logs rejected documents. Response items are in order of request documents
+func (r *ElasticsearchOutput) check(resp *http.Response, body []byte, items []interface{}) error {
+	var res bulkResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	if !res.Errors {
+		return nil
+	}
+
+	rejected := 0
+	for i, item := range res.Items {
+		for _, status := range item {
+			if status.Status < 300 {
+				continue
+			}
+			rejected++
+
+			if r.errorLogger == "" {
+				continue
+			}
+
+			var doc string
+			if i < len(items) {
+				doc = string(items[i].(*document).body)
+			}
+			zapwriter.Logger(r.errorLogger).Error("document rejected by elasticsearch",
+				zap.String("index", status.Index),
+				zap.Int("status", status.Status),
+				zap.String("error", string(status.Error)),
+				zap.String("message", doc),
+			)
+		}
+	}
+
+	return fmt.Errorf("%d of %d documents rejected", rejected, len(items))
+}
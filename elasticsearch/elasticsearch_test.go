@@ -0,0 +1,80 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lomik/zapwriter"
+)
+
+func TestElasticsearch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var actions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+
+		var items []string
+		scanner := bufio.NewScanner(r.Body)
+		for i := 0; scanner.Scan(); i++ {
+			if i%2 == 0 {
+				actions = append(actions, scanner.Text())
+				continue
+			}
+			if strings.Contains(scanner.Text(), "rejected") {
+				items = append(items, `{"index":{"_index":"logs","status":400,"error":{"type":"mapper_parsing_exception"}}}`)
+			} else {
+				items = append(items, `{"index":{"_index":"logs","status":201}}`)
+			}
+		}
+		fmt.Fprintf(w, `{"took":1,"errors":true,"items":[%s]}`, strings.Join(items, ","))
+	}))
+	defer srv.Close()
+
+	es := zapwriter.NewConfig()
+	es.Encoding = "json"
+	es.File = strings.Replace(srv.URL, "http://", "elasticsearch://", 1) + "/?index=logs-app-%Y.%m&error_logger=es_error"
+
+	errorLog := zapwriter.NewConfig()
+	errorLog.Logger = "es_error"
+	errorLog.File = filepath.Join(dir, "error.log")
+
+	if err := zapwriter.ApplyConfig([]zapwriter.Config{es, errorLog}); err != nil {
+		t.Fatal(err)
+	}
+	defer zapwriter.ApplyConfig([]zapwriter.Config{zapwriter.NewConfig()})
+
+	zapwriter.Default().Info("accepted")
+	zapwriter.Default().Info("rejected")
+
+	if err := zapwriter.Sync(); err == nil || err.Error() != "1 of 2 documents rejected" {
+		t.Fatal(err)
+	}
+
+	var action map[string]map[string]string
+	if len(actions) != 2 || json.Unmarshal([]byte(actions[0]), &action) != nil ||
+		action["index"]["_index"] != "logs-app-"+time.Now().Format("2006.01") {
+		t.Fatal(actions)
+	}
+
+	c, _ := ioutil.ReadFile(errorLog.File)
+	if !bytes.Contains(c, []byte("document rejected by elasticsearch")) || !bytes.Contains(c, []byte(`\"message\":\"rejected\"`)) {
+		t.Fatal(string(c))
+	}
+}
@@ -0,0 +1,75 @@
+package zapwriter
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+type entryOutput interface {
+	EntryWriter
+	Sync() error
+}
+
+// entryCore is zapcore.ioCore which passes entry and fields to EntryWriter with encoded message
+type entryCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	out    entryOutput
+	fields []zapcore.Field // added with With
+}
+
+func (c *entryCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &entryCore{
+		LevelEnabler: c.LevelEnabler,
+		enc:          c.enc.Clone(),
+		out:          c.out,
+		fields:       make([]zapcore.Field, 0, len(c.fields)+len(fields)),
+	}
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	addFields(clone.enc, fields)
+	return clone
+}
+
+func (c *entryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *entryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+
+	all := fields
+	if len(c.fields) > 0 {
+		all = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+		all = append(all, c.fields...)
+		all = append(all, fields...)
+	}
+
+	_, err = c.out.WriteEntry(ent, all, buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+
+	if ent.Level > zapcore.ErrorLevel {
+		// Since we may be crashing the program, sync the output.
+		c.Sync()
+	}
+	return nil
+}
+
+func (c *entryCore) Sync() error {
+	return c.out.Sync()
+}
+
+func (c *entryCore) Close() error {
+	if cl, ok := c.out.(closeable); ok {
+		return cl.Close()
+	}
+	return nil
+}
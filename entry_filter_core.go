@@ -0,0 +1,38 @@
+package zapwriter
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// entryFilterCore removes caller and stacktrace added by logger options
+// from entries of output which doesn't request them
+type entryFilterCore struct {
+	zapcore.Core
+	stripCaller bool
+	stacktrace  *zapcore.Level // keep stacktrace at this level and above, nil - never
+}
+
+func (c *entryFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &entryFilterCore{
+		Core:        c.Core.With(fields),
+		stripCaller: c.stripCaller,
+		stacktrace:  c.stacktrace,
+	}
+}
+
+func (c *entryFilterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *entryFilterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.stripCaller {
+		ent.Caller = zapcore.EntryCaller{}
+	}
+	if c.stacktrace == nil || ent.Level < *c.stacktrace {
+		ent.Stack = ""
+	}
+	return c.Core.Write(ent, fields)
+}
@@ -6,19 +6,36 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"sync"
 	"time"
 )
 
 // with external rotate support
+// and optional built-in rotation by size (max-size=100MB) or by schedule (rotate=daily)
 type FileOutput struct {
 	sync.Mutex
-	timeout   time.Duration
-	interval  time.Duration
-	checkNext time.Time
-	f         *os.File
-	path      string // filename
+	timeout       time.Duration
+	interval      time.Duration
+	checkNext     time.Time
+	f             *os.File
+	path          string         // filename
+	size          int64          // bytes written to current file
+	maxSize       int64          // rotate when file grows past this size, 0 - disabled
+	maxBackups    int            // number of rotated files to keep, 0 - keep all
+	backupName    string         // "numbered" (file.log.1) or "timestamp" (file.log.<backup-pattern>)
+	backupPattern string         // strftime-style suffix of timestamp backups
+	backupRe      *regexp.Regexp // matches names of backups after path
+	schedule      rotateSchedule // time-based rotation, nil - disabled
+	rotateNext    time.Time      // next scheduled rotation
+	periodStart   time.Time      // when current file was started, used in backup name
+	maxAge        time.Duration  // remove backups older than this, 0 - keep all
+	compress      string         // compress backups: "", "gzip" or "zstd"
+	cleanup       chan struct{}  // wakes up backupsCleaner, nil if there is nothing to clean
+	backupsMu     sync.Mutex     // protects backup files renaming
+	rotateBackoff time.Duration  // delay of next rotation attempt after failure
+	rotateRetry   time.Time      // no rotation attempts before this time
 
 	exit     chan interface{}
 	exitOnce sync.Once
@@ -44,18 +61,92 @@ func newFileOutput(path string) (*FileOutput, error) {
 		return nil, err
 	}
 
+	maxSize, err := params.Size("max-size", "0")
+	if err != nil {
+		return nil, err
+	}
+
+	maxBackups, err := params.Int("max-backups", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule rotateSchedule
+	var rotateNext time.Time
+	if spec := params.Get("rotate"); spec != "" {
+		if schedule, err = parseSchedule(spec); err != nil {
+			return nil, err
+		}
+		if rotateNext = schedule.Next(time.Now()); rotateNext.IsZero() {
+			return nil, fmt.Errorf("rotate schedule %#v never fires", spec)
+		}
+	}
+
+	defaultBackupName := "numbered"
+	if schedule != nil {
+		defaultBackupName = "timestamp"
+	}
+
+	backupName, err := params.String("backup-name", defaultBackupName)
+	if err != nil {
+		return nil, err
+	}
+
+	if backupName != "numbered" && backupName != "timestamp" {
+		return nil, fmt.Errorf("unknown backup-name %#v", backupName)
+	}
+
+	backupPattern, err := params.String("backup-pattern", "%Y-%m-%dT%H-%M-%S.%L")
+	if err != nil {
+		return nil, err
+	}
+
+	backupRe, err := regexp.Compile(`^\.(\d+|` + strftimeRegexp(backupPattern) + `(-\d+)?)(\.gz|\.zst)?$`)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAge, err := params.Duration("max-age", "0")
+	if err != nil {
+		return nil, err
+	}
+
+	compress, err := params.String("compress", "")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := compressSuffix[compress]; !ok {
+		return nil, fmt.Errorf("unknown compress %#v", compress)
+	}
+
 	f, err := os.OpenFile(u.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, err
 	}
 
+	var size int64
+	if fInfo, err := f.Stat(); err == nil {
+		size = fInfo.Size()
+	}
+
 	r := &FileOutput{
-		checkNext: time.Now().Add(timeout),
-		timeout:   timeout,
-		interval:  interval,
-		f:         f,
-		path:      u.Path,
-		exit:      make(chan interface{}),
+		checkNext:     time.Now().Add(timeout),
+		timeout:       timeout,
+		interval:      interval,
+		f:             f,
+		path:          u.Path,
+		size:          size,
+		maxSize:       maxSize,
+		maxBackups:    maxBackups,
+		backupName:    backupName,
+		backupPattern: backupPattern,
+		backupRe:      backupRe,
+		schedule:      schedule,
+		rotateNext:    rotateNext,
+		periodStart:   time.Now(),
+		maxAge:        maxAge,
+		compress:      compress,
+		exit:          make(chan interface{}),
 	}
 
 	r.exitWg.Add(1)
@@ -64,6 +155,17 @@ func newFileOutput(path string) (*FileOutput, error) {
 		r.exitWg.Done()
 	}()
 
+	if maxBackups > 0 || maxAge > 0 || compress != "" {
+		r.cleanup = make(chan struct{}, 1)
+		r.cleanup <- struct{}{} // backups left from previous run
+
+		r.exitWg.Add(1)
+		go func() {
+			r.backupsCleaner(r.exit)
+			r.exitWg.Done()
+		}()
+	}
+
 	return r, nil
 }
 
@@ -78,7 +180,7 @@ func (r *FileOutput) reopenChecker(exit chan interface{}) {
 	for {
 		select {
 		case <-ticker.C:
-			r.doWithCheck(func() {})
+			r.doWithCheck(func() { r.rotateIfNeeded(0) })
 		case <-exit:
 			return
 		}
@@ -95,11 +197,85 @@ func (r *FileOutput) reopen() *os.File {
 
 	r.f = next
 	prev.Close()
+
+	r.size = 0
+	if fInfo, err := next.Stat(); err == nil {
+		r.size = fInfo.Size()
+	}
+
+	// previous file was moved away by us or by external tool
+	r.wakeCleaner()
+
 	return r.f
 }
 
+// rotateIfNeeded checks schedule and max-size before writing n bytes. Must be called under lock
+func (r *FileOutput) rotateIfNeeded(n int) {
+	now := time.Now()
+
+	// previous rotation failed
+	if now.Before(r.rotateRetry) {
+		return
+	}
+
+	if r.schedule != nil && !now.Before(r.rotateNext) {
+		// nothing to cut. Failed rotation is retried with same period
+		if r.size == 0 || r.rotate(r.periodStart) {
+			r.periodStart = now
+			r.rotateNext = r.schedule.Next(now)
+		}
+		return
+	}
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(n) > r.maxSize {
+		r.rotate(now)
+	}
+}
+
+// rotate moves current file to backup and opens new one. Must be called under lock.
+// After failure next attempt is delayed, from 1 second up to 1 minute. Returns false on failure
+func (r *FileOutput) rotate(t time.Time) bool {
+	if closeBeforeRename {
+		r.f.Close()
+	}
+
+	r.backupsMu.Lock()
+	var err error
+	if r.backupName == "timestamp" {
+		err = os.Rename(r.path, r.timestampBackup(t))
+	} else {
+		err = r.shiftNumberedBackups()
+	}
+	r.backupsMu.Unlock()
+
+	if err != nil {
+		fmt.Println(err.Error())
+		r.rotateBackoff *= 2
+		if r.rotateBackoff < time.Second {
+			r.rotateBackoff = time.Second
+		}
+		if r.rotateBackoff > time.Minute {
+			r.rotateBackoff = time.Minute
+		}
+		r.rotateRetry = time.Now().Add(r.rotateBackoff)
+		if closeBeforeRename {
+			// continue writing to not rotated file
+			r.reopen()
+		}
+		return false
+	}
+
+	r.rotateBackoff = 0
+	r.reopen()
+	return true
+}
+
 func (r *FileOutput) Write(p []byte) (n int, err error) {
-	r.doWithCheck(func() { n, err = r.f.Write(p) })
+	r.doWithCheck(func() {
+		r.rotateIfNeeded(len(p))
+		n, err = r.f.Write(p)
+		r.size += int64(n)
+	})
 	return
 }
 
@@ -0,0 +1,249 @@
+package zapwriter
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var compressSuffix = map[string]string{
+	"":     "",
+	"gzip": ".gz",
+	"gz":   ".gz",
+	"zstd": ".zst",
+}
+
+type backupFile struct {
+	name    string
+	num     int // file.log.<num>, -1 for timestamp backups
+	modTime time.Time
+}
+
+func (b backupFile) compressed() bool {
+	return strings.HasSuffix(b.name, ".gz") || strings.HasSuffix(b.name, ".zst")
+}
+
+func trimCompressSuffix(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".zst")
+}
+
+// existing returns name or its compressed version if exists
+func existing(name string) (string, bool) {
+	for _, suffix := range []string{"", ".gz", ".zst"} {
+		if _, err := os.Stat(name + suffix); err == nil {
+			return name + suffix, true
+		}
+	}
+	return "", false
+}
+
+func (r *FileOutput) timestampBackup(t time.Time) string {
+	name := fmt.Sprintf("%s.%s", r.path, Strftime(r.backupPattern, t))
+	backup := name
+	for i := 1; ; i++ {
+		if _, exists := existing(backup); !exists {
+			return backup
+		}
+		backup = fmt.Sprintf("%s-%d", name, i)
+	}
+}
+
+// file.log.2 -> file.log.3, file.log.1.gz -> file.log.2.gz, file.log -> file.log.1
+func (r *FileOutput) shiftNumberedBackups() error {
+	last := 0
+	for {
+		if _, exists := existing(fmt.Sprintf("%s.%d", r.path, last+1)); !exists {
+			break
+		}
+		last++
+	}
+
+	for i := last; i > 0; i-- {
+		name, _ := existing(fmt.Sprintf("%s.%d", r.path, i))
+		suffix := strings.TrimPrefix(name, trimCompressSuffix(name))
+		if err := os.Rename(name, fmt.Sprintf("%s.%d%s", r.path, i+1, suffix)); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(r.path, r.path+".1")
+}
+
+// backups returns rotated files, newest first. Only <path>.<N> and <path>.<backup-pattern>
+// names (optionally compressed) are backups, other files in directory are never touched
+func (r *FileOutput) backups() ([]backupFile, error) {
+	dir := filepath.Dir(r.path)
+	base := filepath.Base(r.path)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []backupFile
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), base) {
+			continue
+		}
+		m := r.backupRe.FindStringSubmatch(strings.TrimPrefix(f.Name(), base))
+		if m == nil {
+			continue
+		}
+		num := -1
+		if r.backupName == "numbered" {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				num = n
+			}
+		}
+		list = append(list, backupFile{name: filepath.Join(dir, f.Name()), num: num, modTime: f.ModTime()})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].num >= 0 && list[j].num >= 0 {
+			return list[i].num < list[j].num
+		}
+		if !list[i].modTime.Equal(list[j].modTime) {
+			return list[i].modTime.After(list[j].modTime)
+		}
+		return list[i].name > list[j].name
+	})
+
+	return list, nil
+}
+
+func (r *FileOutput) wakeCleaner() {
+	if r.cleanup == nil {
+		return
+	}
+	select {
+	case r.cleanup <- struct{}{}:
+	default: // already scheduled
+	}
+}
+
+func (r *FileOutput) backupsCleaner(exit chan interface{}) {
+	for {
+		select {
+		case <-r.cleanup:
+			r.cleanupBackups(exit)
+		case <-exit:
+			return
+		}
+	}
+}
+
+// cleanupBackups compresses new backups and removes backups beyond max-backups and max-age
+func (r *FileOutput) cleanupBackups(exit chan interface{}) {
+	list, err := r.backups()
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	now := time.Now()
+	for i, b := range list {
+		select {
+		case <-exit:
+			return
+		default:
+		}
+
+		if (r.maxBackups > 0 && i >= r.maxBackups) || (r.maxAge > 0 && now.Sub(b.modTime) > r.maxAge) {
+			r.backupsMu.Lock()
+			err = os.Remove(b.name)
+			r.backupsMu.Unlock()
+			if err != nil && !os.IsNotExist(err) {
+				fmt.Println(err.Error())
+			}
+			continue
+		}
+
+		if r.compress != "" && !b.compressed() {
+			if err := r.compressBackup(b.name); err != nil {
+				fmt.Println(err.Error())
+			}
+		}
+	}
+}
+
+// compressBackup writes compressed copy of backup and removes original.
+// Backup can be renamed by rotation while compressing, in this case compressed copy is dropped
+// and the file will be compressed on next cleanup under new name
+func (r *FileOutput) compressBackup(name string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	srcInfo, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	target := name + compressSuffix[r.compress]
+	tmp := target + ".tmp"
+
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, srcInfo.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	var w io.WriteCloser
+	if r.compress == "zstd" {
+		w, err = zstd.NewWriter(dst)
+		if err != nil {
+			dst.Close()
+			return err
+		}
+	} else {
+		w = gzip.NewWriter(dst)
+	}
+
+	if _, err = io.Copy(w, src); err != nil {
+		w.Close()
+		dst.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+
+	// keep original time for max-age
+	if err = os.Chtimes(tmp, srcInfo.ModTime(), srcInfo.ModTime()); err != nil {
+		return err
+	}
+
+	r.backupsMu.Lock()
+	defer r.backupsMu.Unlock()
+
+	if info, statErr := os.Stat(name); statErr != nil || !os.SameFile(info, srcInfo) {
+		// moved by rotation
+		r.wakeCleaner()
+		return os.Remove(tmp)
+	}
+
+	if err = os.Rename(tmp, target); err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}
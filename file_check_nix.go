@@ -9,6 +9,8 @@ import (
 	"os"
 )
 
+const closeBeforeRename = false
+
 func (r *FileOutput) doWithCheck(f func()) {
 	r.Lock()
 	defer r.Unlock()
@@ -2,6 +2,11 @@
 
 package zapwriter
 
+// open file can't be renamed
+const closeBeforeRename = true
+
 func (r *FileOutput) doWithCheck(f func()) {
+	r.Lock()
+	defer r.Unlock()
 	f()
 }
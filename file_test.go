@@ -1,9 +1,12 @@
 package zapwriter
 
 import (
+	"compress/gzip"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -77,3 +80,275 @@ func TestFileDelete(t *testing.T) {
 
 	f.Close()
 }
+
+func TestFileMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.log")
+
+	f, err := File(path + "?max-size=20&max-backups=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, m := range []string{"first message\n", "second message\n", "third message\n", "fourth message\n"} {
+		f.Write([]byte(m))
+	}
+
+	for file, expected := range map[string]string{
+		path:        "fourth message\n",
+		path + ".1": "third message\n",
+		path + ".2": "second message\n",
+	} {
+		c, err := ioutil.ReadFile(file)
+		if err != nil || string(c) != expected {
+			t.Fatalf("%s: %#v, %v", file, string(c), err)
+		}
+	}
+
+	waitFor(t, func() bool {
+		_, err := os.Stat(path + ".3")
+		return os.IsNotExist(err)
+	})
+}
+
+func TestFileMaxSizeConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	setCloseGracePeriod(t, 0)
+
+	path := filepath.Join(dir, "app.log")
+
+	cfg := NewConfig()
+	cfg.File = path + "?max-size=100B&max-backups=2"
+	if err := ApplyConfig([]Config{cfg}); err != nil {
+		t.Fatal(err)
+	}
+	defer ApplyConfig([]Config{NewConfig()})
+
+	for i := 0; i < 50; i++ {
+		Default().Info("message")
+	}
+
+	if st, err := os.Stat(path); err != nil || st.Size() > 100 {
+		t.Fatalf("%#v, %v", st, err)
+	}
+
+	waitFor(t, func() bool {
+		_, err1 := os.Stat(path + ".2")
+		_, err3 := os.Stat(path + ".3")
+		return err1 == nil && os.IsNotExist(err3)
+	})
+}
+
+func TestFileRotateFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.log")
+
+	// backup directory doesn't exist
+	f, err := File(path + "?max-size=20&backup-name=timestamp&backup-pattern=missing/%25Y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, m := range []string{"first message\n", "second message\n", "third message\n"} {
+		f.Write([]byte(m))
+	}
+
+	f.Lock()
+	retry := f.rotateRetry
+	f.Unlock()
+
+	if time.Until(retry) < 500*time.Millisecond {
+		t.Fatalf("rotation is not delayed: %s", retry)
+	}
+
+	c, err := ioutil.ReadFile(path)
+	if err != nil || string(c) != "first message\nsecond message\nthird message\n" {
+		t.Fatalf("%#v, %v", string(c), err)
+	}
+}
+
+func TestFileRotateSchedule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.log")
+
+	f, err := File(path + "?rotate=daily&backup-pattern=%25Y%25m%25d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("yesterday\n"))
+
+	f.Lock()
+	start := f.periodStart
+	f.rotateNext = time.Now()
+	f.Unlock()
+
+	f.Write([]byte("today\n"))
+
+	c, err := ioutil.ReadFile(path + "." + start.Format("20060102"))
+	if err != nil || string(c) != "yesterday\n" {
+		t.Fatalf("%#v, %v", string(c), err)
+	}
+
+	c, err = ioutil.ReadFile(path)
+	if err != nil || string(c) != "today\n" {
+		t.Fatalf("%#v, %v", string(c), err)
+	}
+}
+
+func TestFileRotateScheduleFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.log")
+
+	// backup directory doesn't exist
+	f, err := File(path + "?rotate=daily&backup-pattern=missing/%25Y%25m%25d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("yesterday\n"))
+
+	f.Lock()
+	start := f.periodStart
+	f.rotateNext = time.Now()
+	f.Unlock()
+
+	f.Write([]byte("today\n"))
+
+	// retry after backoff rotates same period
+	os.Mkdir(path+".missing", 0755)
+	f.Lock()
+	f.rotateRetry = time.Time{}
+	f.Unlock()
+
+	f.Write([]byte("retry\n"))
+
+	c, err := ioutil.ReadFile(path + ".missing/" + start.Format("20060102"))
+	if err != nil || string(c) != "yesterday\ntoday\n" {
+		t.Fatalf("%#v, %v", string(c), err)
+	}
+
+	c, err = ioutil.ReadFile(path)
+	if err != nil || string(c) != "retry\n" {
+		t.Fatalf("%#v, %v", string(c), err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timeout")
+}
+
+func TestFileCompress(t *testing.T) {
+	f, path, dir, tearDown := fileOpen(t)
+	defer tearDown()
+	f.Close()
+
+	old := filepath.Join(dir, "test.log.2")
+	ioutil.WriteFile(old, []byte("too old\n"), 0644)
+	os.Chtimes(old, time.Now().Add(-48*time.Hour), time.Now().Add(-48*time.Hour))
+
+	// not a backup
+	other := filepath.Join(dir, "test.log.old")
+	ioutil.WriteFile(other, []byte("too old\n"), 0644)
+	os.Chtimes(other, time.Now().Add(-48*time.Hour), time.Now().Add(-48*time.Hour))
+
+	f, err := File(path + "?compress=gzip&max-age=1d&timeout=0s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// external rotate
+	os.Rename(path, path+".1")
+	f.Write([]byte("new message\n"))
+
+	waitFor(t, func() bool {
+		_, err := os.Stat(path + ".1")
+		return os.IsNotExist(err)
+	})
+
+	gz, err := os.Open(path + ".1.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gz.Close()
+
+	r, err := gzip.NewReader(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ioutil.ReadAll(r)
+	if err != nil || string(c) != "hello world\n" {
+		t.Fatalf("%#v, %v", string(c), err)
+	}
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatal("old backup not removed")
+	}
+
+	if _, err := os.Stat(other); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileBackups(t *testing.T) {
+	f, path, _, tearDown := fileOpen(t)
+	defer tearDown()
+	defer f.Close()
+
+	for _, suffix := range []string{".1", ".2.gz", ".3.zst", ".2021-06-05T03-04-05.006", ".2021-06-05T03-04-05.006-1.gz",
+		".old", ".err", ".1.gz.tmp", ".1x", "-2021-06-05", "-old.1"} {
+		ioutil.WriteFile(path+suffix, nil, 0644)
+	}
+
+	list, err := f.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, b := range list {
+		names = append(names, strings.TrimPrefix(b.name, path))
+	}
+	sort.Strings(names)
+
+	if strings.Join(names, " ") != ".1 .2.gz .2021-06-05T03-04-05.006 .2021-06-05T03-04-05.006-1.gz .3.zst" {
+		t.Fatal(names)
+	}
+}
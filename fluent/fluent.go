@@ -0,0 +1,393 @@
+package fluent
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lomik/zapwriter"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	zapwriter.RegisterScheme("fluent", New)
+}
+
+// FluentOutput sends entries to fluentd or fluent-bit using Forward protocol in PackedForward mode.
+// Entries are buffered per tag and flushed in background every flush-interval and on buffer-size overflow,
+// writes never wait for network. Sync flushes synchronously. Chunks waiting for flush and chunks which
+// were not sent or not acknowledged are kept (at most buffer-size each, oldest are dropped)
+// and sent again on next flush. Record contains fields of entry as is, encoded message is not used.
+//
+// DSN examples:
+//
+//	fluent://127.0.0.1:24224?tag=app.{logger}
+//	fluent://127.0.0.1:24224?tag=app.{logger}&ack=true&flush-interval=1s&buffer-size=1MB
+//	fluent:///var/run/fluent.sock
+type FluentOutput struct {
+	sync.Mutex
+	network       string
+	address       string
+	tag           string // "{logger}" is replaced with logger name
+	ack           bool   // request chunk ack from server
+	timeout       time.Duration
+	flushInterval time.Duration
+	bufferSize    int64
+	errorLogger   string // fallback logger
+
+	buffers map[string]*chunk // by tag
+	tags    []string          // tags in order of first appearance
+	size    int64             // buffered bytes
+	cut     []*chunk          // buffers cut on overflow and waiting for flusher, oldest first
+	cutSize int64
+
+	failed     []*chunk // not sent chunks, oldest first. Protected by sendMu
+	failedSize int64
+
+	sendMu sync.Mutex
+	conn   net.Conn
+
+	flushCh  chan struct{} // wakes flusher on overflow
+	exit     chan interface{}
+	exitOnce sync.Once
+	exitWg   sync.WaitGroup
+}
+
+type chunk struct {
+	tag     string
+	entries []byte // concatenated [time, record] arrays
+	count   int
+	id      string // chunk id for ack, kept on resend
+}
+
+func New(path string) (zapwriter.Output, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	network, address := "tcp", u.Host
+	if address == "" {
+		network, address = "unix", u.Path
+	}
+	if address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+
+	params := zapwriter.DSN(u.Query())
+
+	r := &FluentOutput{
+		network: network,
+		address: address,
+		buffers: make(map[string]*chunk),
+		flushCh: make(chan struct{}, 1),
+		exit:    make(chan interface{}),
+	}
+
+	if err := zapwriter.AnyError(
+		params.SetString(&r.tag, "tag"),
+		params.SetDuration(&r.timeout, "timeout"),
+		params.SetDuration(&r.flushInterval, "flush-interval"),
+		params.SetString(&r.errorLogger, "error_logger"),
+	); err != nil {
+		return nil, err
+	}
+
+	if r.tag == "" {
+		r.tag = filepath.Base(os.Args[0]) + ".{logger}"
+	}
+	if r.timeout == 0 {
+		r.timeout = 5 * time.Second
+	}
+	if r.flushInterval == 0 {
+		r.flushInterval = time.Second
+	}
+	if r.bufferSize, err = params.Size("buffer-size", "1MB"); err != nil {
+		return nil, err
+	}
+	if r.ack, err = params.Bool("ack", false); err != nil {
+		return nil, err
+	}
+
+	r.exitWg.Add(1)
+	go func() {
+		r.flusher(r.exit)
+		r.exitWg.Done()
+	}()
+
+	return r, nil
+}
+
+// expandTag returns tag of logger. Empty segments are removed, so "app.{logger}" of root logger is "app"
+func (r *FluentOutput) expandTag(loggerName string) string {
+	tag := strings.Replace(r.tag, "{logger}", loggerName, -1)
+	if !strings.Contains(tag, "..") && !strings.HasPrefix(tag, ".") && !strings.HasSuffix(tag, ".") {
+		return tag
+	}
+
+	parts := strings.Split(tag, ".")
+	res := parts[:0]
+	for _, p := range parts {
+		if p != "" {
+			res = append(res, p)
+		}
+	}
+	return strings.Join(res, ".")
+}
+
+// record returns [time, record] msgpack array of entry
+func record(ent zapcore.Entry, fields []zapcore.Field) []byte {
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	enc.Fields["message"] = ent.Message
+	enc.Fields["level"] = ent.Level.String()
+	if ent.LoggerName != "" {
+		enc.Fields["logger"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		enc.Fields["caller"] = ent.Caller.TrimmedPath()
+	}
+	if ent.Stack != "" {
+		enc.Fields["stacktrace"] = ent.Stack
+	}
+
+	b := appendArrayHeader(nil, 2)
+	b = appendEventTime(b, ent.Time)
+	return appendMap(b, enc.Fields)
+}
+
+func (r *FluentOutput) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) (int, error) {
+	rec := record(ent, fields)
+	tag := r.expandTag(ent.LoggerName)
+
+	r.Lock()
+	c := r.buffers[tag]
+	if c == nil {
+		c = &chunk{tag: tag}
+		r.buffers[tag] = c
+		r.tags = append(r.tags, tag)
+	}
+	c.entries = append(c.entries, rec...)
+	c.count++
+	r.size += int64(len(rec))
+
+	var dropped int
+	if r.size >= r.bufferSize {
+		// flusher may be busy with unavailable server, cut chunks wait for it
+		for _, tag := range r.tags {
+			r.cut = append(r.cut, r.buffers[tag])
+		}
+		r.cutSize += r.size
+		r.buffers, r.tags, r.size = make(map[string]*chunk), nil, 0
+		r.cut, dropped = trim(r.cut, &r.cutSize, r.bufferSize)
+
+		select {
+		case r.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	r.Unlock()
+
+	if dropped > 0 {
+		return 0, fmt.Errorf("buffer is full, %d entries dropped", dropped)
+	}
+
+	return len(p), nil
+}
+
+// Write sends message as "message" field of info entry if entry is unknown
+func (r *FluentOutput) Write(p []byte) (int, error) {
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: strings.TrimRight(string(p), "\n")}
+	return r.WriteEntry(ent, nil, p)
+}
+
+func (r *FluentOutput) logError(msg string, err error) {
+	if r.errorLogger != "" {
+		zapwriter.Logger(r.errorLogger).Error(msg, zap.String("address", r.address), zap.Error(err))
+	}
+}
+
+func (r *FluentOutput) flusher(exit chan interface{}) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.flushCh:
+		case <-exit:
+			return
+		}
+
+		if err := r.flush(); err != nil {
+			r.logError("flush failed", err)
+		}
+	}
+}
+
+// flush sends previously failed and buffered chunks in order. Sending stops on first error,
+// the rest is kept for next flush
+func (r *FluentOutput) flush() error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	r.Lock()
+	cut, buffers, tags := r.cut, r.buffers, r.tags
+	r.failedSize += r.cutSize + r.size
+	r.cut, r.cutSize = nil, 0
+	r.buffers, r.tags, r.size = make(map[string]*chunk), nil, 0
+	r.Unlock()
+
+	chunks := append(r.failed, cut...)
+	for _, tag := range tags {
+		chunks = append(chunks, buffers[tag])
+	}
+	r.failed = nil
+
+	for i, c := range chunks {
+		if err := r.send(c); err != nil {
+			r.failed = chunks[i:]
+			return zapwriter.AnyError(err, r.trimFailed())
+		}
+		r.failedSize -= int64(len(c.entries))
+	}
+
+	return nil
+}
+
+// trimFailed drops oldest failed chunks beyond buffer-size. Must be called under sendMu
+func (r *FluentOutput) trimFailed() error {
+	var dropped int
+	r.failed, dropped = trim(r.failed, &r.failedSize, r.bufferSize)
+	if dropped > 0 {
+		return fmt.Errorf("buffer is full, %d entries dropped", dropped)
+	}
+	return nil
+}
+
+// trim drops oldest chunks while size is above limit, the last chunk is kept.
+// Returns rest of chunks and number of dropped entries
+func trim(chunks []*chunk, size *int64, limit int64) ([]*chunk, int) {
+	var dropped int
+	for len(chunks) > 1 && *size > limit {
+		dropped += chunks[0].count
+		*size -= int64(len(chunks[0].entries))
+		chunks = chunks[1:]
+	}
+	return chunks, dropped
+}
+
+// message returns PackedForward message [tag, entries, option]. Chunk id is generated on first call if ack is enabled
+func (r *FluentOutput) message(c *chunk) ([]byte, error) {
+	if r.ack && c.id == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+		c.id = base64.StdEncoding.EncodeToString(id)
+	}
+
+	b := make([]byte, 0, len(c.entries)+len(c.tag)+64)
+	b = appendArrayHeader(b, 3)
+	b = appendString(b, c.tag)
+	b = appendBinary(b, c.entries)
+	if c.id != "" {
+		b = appendMapHeader(b, 2)
+		b = appendString(b, "size")
+		b = appendInt(b, int64(c.count))
+		b = appendString(b, "chunk")
+		b = appendString(b, c.id)
+	} else {
+		b = appendMapHeader(b, 1)
+		b = appendString(b, "size")
+		b = appendInt(b, int64(c.count))
+	}
+	return b, nil
+}
+
+// send writes chunk with one reconnect attempt. Must be called under sendMu
+func (r *FluentOutput) send(c *chunk) error {
+	msg, err := r.message(c)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if r.conn == nil {
+			if r.conn, err = net.DialTimeout(r.network, r.address, r.timeout); err != nil {
+				r.conn = nil
+				continue
+			}
+		}
+
+		r.conn.SetDeadline(time.Now().Add(r.timeout))
+		if _, err = r.conn.Write(msg); err == nil && c.id != "" {
+			err = r.readAck(c.id)
+		}
+		if err == nil {
+			return nil
+		}
+
+		r.conn.Close()
+		r.conn = nil
+	}
+
+	return err
+}
+
+func (r *FluentOutput) readAck(chunkID string) error {
+	var data []byte
+	buf := make([]byte, 256)
+	for {
+		n, err := r.conn.Read(buf)
+		data = append(data, buf[:n]...)
+
+		resp, _, uerr := unpack(data)
+		if uerr == nil {
+			m, ok := resp.(map[string]interface{})
+			if !ok || m["ack"] != chunkID {
+				return fmt.Errorf("unexpected ack response %#v", resp)
+			}
+			return nil
+		}
+		if uerr != io.ErrUnexpectedEOF {
+			return uerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *FluentOutput) Sync() error {
+	return r.flush()
+}
+
+func (r *FluentOutput) Close() (err error) {
+	r.exitOnce.Do(func() {
+		close(r.exit)
+	})
+	r.exitWg.Wait()
+
+	err = r.flush()
+
+	r.sendMu.Lock()
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+	r.sendMu.Unlock()
+	return
+}
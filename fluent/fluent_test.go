@@ -0,0 +1,257 @@
+package fluent
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lomik/zapwriter"
+	"go.uber.org/zap"
+)
+
+// readMessage reads one forward protocol message from conn
+func readMessage(t *testing.T, conn net.Conn) []interface{} {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var data []byte
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		data = append(data, buf[:n]...)
+		msg, _, uerr := unpack(data)
+		if uerr == nil {
+			return msg.([]interface{})
+		}
+		if uerr != io.ErrUnexpectedEOF {
+			t.Fatal(uerr)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFluentPackedForward(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	cfg := zapwriter.NewConfig()
+	cfg.File = fmt.Sprintf("fluent://%s?tag=app.{logger}&ack=true&flush-interval=1h", ln.Addr().String())
+
+	logger, err := cfg.BuildLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	access := logger.Named("access").With(zap.String("host", "example.com"))
+	access.Info("first", zap.Int("code", 200), zap.Any("tags", []string{"a", "b"}))
+	access.Warn("second", zap.Namespace("request"), zap.Int64("size", -100000))
+	logger.Info("root")
+
+	synced := make(chan error)
+	go func() { synced <- logger.Sync() }()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	expected := []struct {
+		tag     string
+		records []map[string]interface{}
+	}{
+		{"app.access", []map[string]interface{}{
+			{"message": "first", "level": "info", "logger": "access", "host": "example.com", "code": uint64(200)},
+			{"message": "second", "level": "warn", "logger": "access", "host": "example.com"},
+		}},
+		{"app", []map[string]interface{}{
+			{"message": "root", "level": "info"},
+		}},
+	}
+
+	for _, e := range expected {
+		msg := readMessage(t, conn)
+		if len(msg) != 3 || msg[0] != e.tag {
+			t.Fatalf("%#v", msg)
+		}
+
+		option := msg[2].(map[string]interface{})
+		if option["size"] != int64(len(e.records)) {
+			t.Fatalf("%#v", option)
+		}
+
+		entries := msg[1].([]byte)
+		for _, expectedRecord := range e.records {
+			var ev interface{}
+			if ev, entries, err = unpack(entries); err != nil {
+				t.Fatal(err)
+			}
+			event := ev.([]interface{})
+			if _, ok := event[0].(time.Time); !ok {
+				t.Fatalf("%#v", event)
+			}
+			record := event[1].(map[string]interface{})
+			for k, v := range expectedRecord {
+				if record[k] != v {
+					t.Fatalf("%s: %#v", k, record)
+				}
+			}
+		}
+		if len(entries) != 0 {
+			t.Fatal("unexpected entries")
+		}
+
+		resp := appendMapHeader(nil, 1)
+		resp = appendString(resp, "ack")
+		resp = appendString(resp, option["chunk"].(string))
+		if _, err := conn.Write(resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := <-synced; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFluentRecordStructure(t *testing.T) {
+	r := &FluentOutput{tag: "app.{logger}"}
+	if tag := r.expandTag("a.b"); tag != "app.a.b" {
+		t.Fatal(tag)
+	}
+
+	b := appendValue(nil, map[string]interface{}{
+		"tags": []interface{}{"a", int64(-1)},
+		"obj":  struct{ X int }{X: 1},
+		"f":    1.5,
+	})
+	v, rest, err := unpack(b)
+	if err != nil || len(rest) != 0 {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	if m["f"] != 1.5 || m["tags"].([]interface{})[1] != int64(-1) || m["obj"].(map[string]interface{})["X"] != 1.0 {
+		t.Fatalf("%#v", m)
+	}
+}
+
+func TestFluentRetryNotAcked(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	out, err := New(fmt.Sprintf("fluent://%s?tag=app&ack=true&flush-interval=1h&timeout=1s", ln.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.(*FluentOutput).Close()
+
+	out.Write([]byte("message\n"))
+
+	// answers wrong ack to both attempts of first Sync, then correct one
+	var mu sync.Mutex
+	var chunks []string
+	go func() {
+		for i := 0; i < 3; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			msg := readMessage(t, conn)
+			chunk := msg[2].(map[string]interface{})["chunk"].(string)
+			mu.Lock()
+			chunks = append(chunks, chunk)
+			mu.Unlock()
+			if i < 2 {
+				chunk = "wrong"
+			}
+			resp := appendMapHeader(nil, 1)
+			resp = appendString(resp, "ack")
+			resp = appendString(resp, chunk)
+			conn.Write(resp)
+			if i < 2 {
+				conn.Close()
+			}
+		}
+	}()
+
+	if err := out.Sync(); err == nil {
+		t.Fatal("error expected")
+	}
+
+	if err := out.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(chunks) != 3 || chunks[0] != chunks[2] {
+		t.Fatalf("%#v", chunks)
+	}
+}
+
+func TestFluentWriteNotBlocked(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// server accepts connections and never answers ack
+	var mu sync.Mutex
+	var conns []net.Conn
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+
+	out, err := New(fmt.Sprintf("fluent://%s?tag=app&ack=true&flush-interval=1h&timeout=1s&buffer-size=200", ln.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.(*FluentOutput).Close()
+
+	// server goes away before Close
+	defer func() {
+		ln.Close()
+		mu.Lock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+		mu.Unlock()
+	}()
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		out.Write([]byte("message\n"))
+	}
+
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("writes blocked for %s", d)
+	}
+
+	r := out.(*FluentOutput)
+	r.Lock()
+	buffered := r.cutSize + r.size
+	r.Unlock()
+
+	if buffered > 400 {
+		t.Fatalf("buffer is not trimmed: %d bytes", buffered)
+	}
+}
@@ -0,0 +1,340 @@
+package fluent
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Minimal msgpack implementation, enough for forward protocol
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v>>32)), uint32(v))
+}
+
+func appendNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return append(b, 0xd1, byte(v>>8), byte(v))
+	case v >= math.MinInt32:
+		return appendUint32(append(b, 0xd2), uint32(v))
+	}
+	return appendUint64(append(b, 0xd3), uint64(v))
+}
+
+func appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v < 128:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return append(b, 0xcd, byte(v>>8), byte(v))
+	case v <= math.MaxUint32:
+		return appendUint32(append(b, 0xce), uint32(v))
+	}
+	return appendUint64(append(b, 0xcf), v)
+}
+
+func appendFloat(b []byte, v float64) []byte {
+	return appendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+func appendString(b []byte, v string) []byte {
+	n := len(v)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = appendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, v...)
+}
+
+func appendBinary(b []byte, v []byte) []byte {
+	n := len(v)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xc5, byte(n>>8), byte(n))
+	default:
+		b = appendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, v...)
+}
+
+func appendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xdc, byte(n>>8), byte(n))
+	}
+	return appendUint32(append(b, 0xdd), uint32(n))
+}
+
+func appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xde, byte(n>>8), byte(n))
+	}
+	return appendUint32(append(b, 0xdf), uint32(n))
+}
+
+// appendEventTime appends EventTime extension (nanosecond precision timestamp)
+func appendEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = appendUint32(b, uint32(t.Unix()))
+	return appendUint32(b, uint32(t.Nanosecond()))
+}
+
+// appendValue appends values produced by zapcore.MapObjectEncoder
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return appendNil(b)
+	case bool:
+		return appendBool(b, v)
+	case string:
+		return appendString(b, v)
+	case []byte:
+		return appendBinary(b, v)
+	case int:
+		return appendInt(b, int64(v))
+	case int8:
+		return appendInt(b, int64(v))
+	case int16:
+		return appendInt(b, int64(v))
+	case int32:
+		return appendInt(b, int64(v))
+	case int64:
+		return appendInt(b, v)
+	case uint:
+		return appendUint(b, uint64(v))
+	case uint8:
+		return appendUint(b, uint64(v))
+	case uint16:
+		return appendUint(b, uint64(v))
+	case uint32:
+		return appendUint(b, uint64(v))
+	case uint64:
+		return appendUint(b, v)
+	case uintptr:
+		return appendUint(b, uint64(v))
+	case float32:
+		return appendFloat(b, float64(v))
+	case float64:
+		return appendFloat(b, v)
+	case time.Time:
+		return appendString(b, v.Format(time.RFC3339Nano))
+	case time.Duration:
+		return appendString(b, v.String())
+	case []interface{}:
+		b = appendArrayHeader(b, len(v))
+		for _, e := range v {
+			b = appendValue(b, e)
+		}
+		return b
+	case map[string]interface{}:
+		return appendMap(b, v)
+	case fmt.Stringer:
+		return appendString(b, v.String())
+	}
+
+	// reflected objects: keep structure via json
+	if data, err := json.Marshal(v); err == nil {
+		var generic interface{}
+		if err := json.Unmarshal(data, &generic); err == nil {
+			return appendValue(b, generic)
+		}
+	}
+	return appendString(b, fmt.Sprint(v))
+}
+
+// appendMap appends map with sorted keys
+func appendMap(b []byte, m map[string]interface{}) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b = appendMapHeader(b, len(keys))
+	for _, k := range keys {
+		b = appendString(b, k)
+		b = appendValue(b, m[k])
+	}
+	return b
+}
+
+// unpack decodes one value from the beginning of b. Returns io.ErrUnexpectedEOF if b is incomplete.
+// Maps are decoded to map[string]interface{}, EventTime to time.Time
+func unpack(b []byte) (interface{}, []byte, error) {
+	if len(b) == 0 {
+		return nil, b, io.ErrUnexpectedEOF
+	}
+
+	need := func(n int) error {
+		if len(b) < n {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+
+	c := b[0]
+	b = b[1:]
+
+	var n int
+	switch {
+	case c <= 0x7f:
+		return int64(c), b, nil
+	case c >= 0xe0:
+		return int64(int8(c)), b, nil
+	case c&0xe0 == 0xa0:
+		n = int(c & 0x1f)
+		if err := need(n); err != nil {
+			return nil, b, err
+		}
+		return string(b[:n]), b[n:], nil
+	case c&0xf0 == 0x90:
+		return unpackArray(b, int(c&0x0f))
+	case c&0xf0 == 0x80:
+		return unpackMap(b, int(c&0x0f))
+	}
+
+	sizes := map[byte]int{
+		0xcc: 1, 0xcd: 2, 0xce: 4, 0xcf: 8,
+		0xd0: 1, 0xd1: 2, 0xd2: 4, 0xd3: 8,
+		0xca: 4, 0xcb: 8,
+		0xc4: 1, 0xc5: 2, 0xc6: 4,
+		0xd9: 1, 0xda: 2, 0xdb: 4,
+		0xdc: 2, 0xdd: 4, 0xde: 2, 0xdf: 4,
+		0xd7: 9,
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2:
+		return false, b, nil
+	case 0xc3:
+		return true, b, nil
+	}
+
+	size, ok := sizes[c]
+	if !ok {
+		return nil, b, fmt.Errorf("unsupported msgpack type 0x%02x", c)
+	}
+	if err := need(size); err != nil {
+		return nil, b, err
+	}
+
+	var u uint64
+	if c != 0xd7 {
+		for _, x := range b[:size] {
+			u = u<<8 | uint64(x)
+		}
+	}
+	head := b[:size]
+	b = b[size:]
+
+	switch c {
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return u, b, nil
+	case 0xd0:
+		return int64(int8(u)), b, nil
+	case 0xd1:
+		return int64(int16(u)), b, nil
+	case 0xd2:
+		return int64(int32(u)), b, nil
+	case 0xd3:
+		return int64(u), b, nil
+	case 0xca:
+		return float64(math.Float32frombits(uint32(u))), b, nil
+	case 0xcb:
+		return math.Float64frombits(u), b, nil
+	case 0xc4, 0xc5, 0xc6:
+		n = int(u)
+		if err := need(n); err != nil {
+			return nil, b, err
+		}
+		return append([]byte(nil), b[:n]...), b[n:], nil
+	case 0xd9, 0xda, 0xdb:
+		n = int(u)
+		if err := need(n); err != nil {
+			return nil, b, err
+		}
+		return string(b[:n]), b[n:], nil
+	case 0xdc, 0xdd:
+		return unpackArray(b, int(u))
+	case 0xde, 0xdf:
+		return unpackMap(b, int(u))
+	}
+
+	// 0xd7: fixext8
+	if head[0] != 0x00 {
+		return nil, b, fmt.Errorf("unsupported msgpack extension %d", head[0])
+	}
+	sec := binary.BigEndian.Uint32(head[1:5])
+	nsec := binary.BigEndian.Uint32(head[5:9])
+	return time.Unix(int64(sec), int64(nsec)), b, nil
+}
+
+func unpackArray(b []byte, n int) (interface{}, []byte, error) {
+	res := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		var err error
+		if res[i], b, err = unpack(b); err != nil {
+			return nil, b, err
+		}
+	}
+	return res, b, nil
+}
+
+func unpackMap(b []byte, n int) (interface{}, []byte, error) {
+	res := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		var k, v interface{}
+		var err error
+		if k, b, err = unpack(b); err != nil {
+			return nil, b, err
+		}
+		if v, b, err = unpack(b); err != nil {
+			return nil, b, err
+		}
+		res[fmt.Sprint(k)] = v
+	}
+	return res, b, nil
+}
@@ -0,0 +1,156 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"fmt"
+	"net/url"
+
+	"github.com/lomik/zapwriter"
+	"github.com/lomik/zapwriter/socket"
+)
+
+func init() {
+	zapwriter.RegisterScheme("gelf+udp", New)
+	zapwriter.RegisterScheme("gelf+tcp", New)
+}
+
+const maxChunks = 128
+
+var chunkMagic = []byte{0x1e, 0x0f}
+
+// GELFOutput sends messages encoded with "gelf" encoding to Graylog.
+// UDP messages are gzipped and chunked, TCP messages are framed with null byte.
+// Messages are queued and sent in background, connection is restored with backoff.
+//
+// DSN examples:
+//
+//	gelf+udp://graylog:12201?chunk-size=1420&compress=gzip
+//	gelf+tcp://graylog:12201?write-timeout=1s&drop-policy=newest
+//
+// Queue and reconnect params are described in socket.ParseOptions, timeout is alias of write-timeout
+type GELFOutput struct {
+	*socket.Writer
+	network   string
+	compress  bool
+	chunkSize int
+}
+
+func New(path string) (zapwriter.Output, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	params := zapwriter.DSN(u.Query())
+
+	network := "udp"
+	if u.Scheme == "gelf+tcp" {
+		network = "tcp"
+	}
+
+	defaultCompress := "gzip"
+	if network == "tcp" {
+		// Graylog doesn't support compression over TCP
+		defaultCompress = "none"
+	}
+
+	compress, err := params.String("compress", defaultCompress)
+	if err != nil {
+		return nil, err
+	}
+	if compress != "gzip" && compress != "none" {
+		return nil, fmt.Errorf("unknown compress %#v", compress)
+	}
+	if compress == "gzip" && network == "tcp" {
+		return nil, fmt.Errorf("compress is not supported by gelf+tcp")
+	}
+
+	chunkSize, err := params.Int("chunk-size", 1420)
+	if err != nil {
+		return nil, err
+	}
+	// 12 bytes of chunk header
+	if chunkSize <= 12 {
+		return nil, fmt.Errorf("chunk-size is too small")
+	}
+
+	opts, err := socket.ParseOptions(u, network, u.Host)
+	if err != nil {
+		return nil, err
+	}
+	if err := params.SetDuration(&opts.WriteTimeout, "timeout"); err != nil {
+		return nil, err
+	}
+
+	return &GELFOutput{
+		Writer:    socket.NewWriter(opts),
+		network:   network,
+		compress:  compress == "gzip",
+		chunkSize: chunkSize,
+	}, nil
+}
+
+// packets returns datagrams or stream frames of message p
+func (g *GELFOutput) packets(p []byte) ([][]byte, error) {
+	if g.network == "tcp" {
+		frame := make([]byte, 0, len(p)+1)
+		frame = append(frame, p...)
+		return [][]byte{append(frame, 0)}, nil
+	}
+
+	if g.compress {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(p); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		p = buf.Bytes()
+	} else {
+		p = append([]byte(nil), p...)
+	}
+
+	if len(p) <= g.chunkSize {
+		return [][]byte{p}, nil
+	}
+
+	dataSize := g.chunkSize - 12
+	count := (len(p) + dataSize - 1) / dataSize
+	if count > maxChunks {
+		return nil, fmt.Errorf("message is too large: %d chunks", count)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		data := p[i*dataSize:]
+		if len(data) > dataSize {
+			data = data[:dataSize]
+		}
+		chunk := make([]byte, 0, 12+len(data))
+		chunk = append(chunk, chunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunks = append(chunks, append(chunk, data...))
+	}
+	return chunks, nil
+}
+
+func (g *GELFOutput) Write(p []byte) (int, error) {
+	packets, err := g.packets(bytes.TrimRight(p, "\n"))
+	if err != nil {
+		return 0, err
+	}
+	if err := g.Send(packets...); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
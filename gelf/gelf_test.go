@@ -0,0 +1,152 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lomik/zapwriter"
+)
+
+func TestGELFUDPChunked(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	cfg := zapwriter.NewConfig()
+	cfg.File = fmt.Sprintf("gelf+udp://%s?chunk-size=100", conn.LocalAddr().String())
+	cfg.Encoding = "gelf"
+
+	logger, err := cfg.BuildLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// random-ish payload which is not compressed into single chunk
+	text := make([]string, 100)
+	for i := range text {
+		text[i] = fmt.Sprintf("%x", i*7919)
+	}
+	logger.Info(strings.Join(text, " "))
+
+	chunks := make(map[byte][]byte)
+	var count byte
+	buf := make([]byte, 4096)
+	for count == 0 || len(chunks) < int(count) {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n > 100 || !bytes.Equal(buf[:2], chunkMagic) {
+			t.Fatalf("bad chunk %#v", buf[:n])
+		}
+		chunks[buf[10]] = append([]byte(nil), buf[12:n]...)
+		count = buf[11]
+	}
+
+	if count < 2 {
+		t.Fatal("message is not chunked")
+	}
+
+	var body []byte
+	for i := byte(0); i < count; i++ {
+		body = append(body, chunks[i]...)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatal(err, string(body))
+	}
+	if msg["short_message"] != strings.Join(text, " ") || msg["version"] != "1.1" {
+		t.Fatal(string(body))
+	}
+}
+
+func TestGELFTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	cfg := zapwriter.NewConfig()
+	cfg.File = fmt.Sprintf("gelf+tcp://%s", ln.Addr().String())
+	cfg.Encoding = "gelf"
+
+	logger, err := cfg.BuildLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("first")
+	logger.Info("second")
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var data []byte
+	buf := make([]byte, 4096)
+	for bytes.Count(data, []byte{0}) < 2 {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, buf[:n]...)
+	}
+
+	frames := bytes.Split(bytes.TrimRight(data, "\x00"), []byte{0})
+	for i, expected := range []string{"first", "second"} {
+		var msg map[string]interface{}
+		if err := json.Unmarshal(frames[i], &msg); err != nil {
+			t.Fatal(err, string(data))
+		}
+		if msg["short_message"] != expected {
+			t.Fatal(string(data))
+		}
+	}
+}
+
+func TestGELFUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	out, err := New(fmt.Sprintf("gelf+tcp://%s?timeout=1s&queue-size=10&sync-timeout=10ms", addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.(*GELFOutput).Close()
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		out.Write([]byte(`{"short_message":"message"}`))
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("writes blocked for %s", d)
+	}
+}
@@ -0,0 +1,171 @@
+package zapwriter
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// gelfEncoder writes GELF 1.1 messages. Extra fields are prefixed with "_",
+// namespaces and nested objects are flattened with "_" separator
+type gelfEncoder struct {
+	*jsonEncoder
+	host   string
+	prefix string // key prefix of current namespace, without leading "_"
+}
+
+func NewGELFEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return &gelfEncoder{jsonEncoder: newJSONEncoder(cfg, false), host: host}
+}
+
+func gelfKeyChar(r rune) rune {
+	if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+		return r
+	}
+	return '_'
+}
+
+func (enc *gelfEncoder) key(key string) string {
+	key = enc.prefix + key
+	// "_id" is reserved by Graylog
+	if key == "id" {
+		key = "id_"
+	}
+	return "_" + strings.Map(gelfKeyChar, key)
+}
+
+func (enc *gelfEncoder) nested(key string) *gelfEncoder {
+	return &gelfEncoder{jsonEncoder: enc.jsonEncoder, host: enc.host, prefix: enc.prefix + key + "_"}
+}
+
+// addRaw adds value encoded by fn as string. GELF fields are strings or numbers only
+func (enc *gelfEncoder) addRaw(key string, fn func(*jsonEncoder) error) error {
+	tmp := enc.jsonEncoder.clone()
+	defer func() {
+		tmp.buf.Free()
+		putJSONEncoder(tmp)
+	}()
+	if err := fn(tmp); err != nil {
+		return err
+	}
+	enc.AddString(key, tmp.buf.String())
+	return nil
+}
+
+func (enc *gelfEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	return enc.addRaw(key, func(tmp *jsonEncoder) error { return tmp.AppendArray(arr) })
+}
+
+func (enc *gelfEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	return obj.MarshalLogObject(enc.nested(key))
+}
+
+func (enc *gelfEncoder) AddReflected(key string, obj interface{}) error {
+	marshaled, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	if len(marshaled) > 0 && (marshaled[0] == '{' || marshaled[0] == '[') {
+		enc.AddString(key, string(marshaled))
+		return nil
+	}
+	enc.addKey(enc.key(key))
+	_, err = enc.buf.Write(marshaled)
+	return err
+}
+
+func (enc *gelfEncoder) OpenNamespace(key string) {
+	enc.prefix = enc.prefix + key + "_"
+}
+
+func (enc *gelfEncoder) AddBinary(k string, v []byte) { enc.jsonEncoder.AddBinary(enc.key(k), v) }
+func (enc *gelfEncoder) AddByteString(k string, v []byte) {
+	enc.jsonEncoder.AddByteString(enc.key(k), v)
+}
+func (enc *gelfEncoder) AddBool(k string, v bool) { enc.AddString(k, strconv.FormatBool(v)) }
+func (enc *gelfEncoder) AddComplex128(k string, v complex128) {
+	enc.jsonEncoder.AddComplex128(enc.key(k), v)
+}
+func (enc *gelfEncoder) AddComplex64(k string, v complex64) { enc.AddComplex128(k, complex128(v)) }
+func (enc *gelfEncoder) AddDuration(k string, v time.Duration) {
+	enc.jsonEncoder.AddDuration(enc.key(k), v)
+}
+func (enc *gelfEncoder) AddFloat64(k string, v float64) { enc.jsonEncoder.AddFloat64(enc.key(k), v) }
+func (enc *gelfEncoder) AddFloat32(k string, v float32) { enc.AddFloat64(k, float64(v)) }
+func (enc *gelfEncoder) AddInt64(k string, v int64)     { enc.jsonEncoder.AddInt64(enc.key(k), v) }
+func (enc *gelfEncoder) AddInt(k string, v int)         { enc.AddInt64(k, int64(v)) }
+func (enc *gelfEncoder) AddInt32(k string, v int32)     { enc.AddInt64(k, int64(v)) }
+func (enc *gelfEncoder) AddInt16(k string, v int16)     { enc.AddInt64(k, int64(v)) }
+func (enc *gelfEncoder) AddInt8(k string, v int8)       { enc.AddInt64(k, int64(v)) }
+func (enc *gelfEncoder) AddString(k, v string)          { enc.jsonEncoder.AddString(enc.key(k), v) }
+func (enc *gelfEncoder) AddTime(k string, v time.Time)  { enc.jsonEncoder.AddTime(enc.key(k), v) }
+func (enc *gelfEncoder) AddUint64(k string, v uint64)   { enc.jsonEncoder.AddUint64(enc.key(k), v) }
+func (enc *gelfEncoder) AddUint(k string, v uint)       { enc.AddUint64(k, uint64(v)) }
+func (enc *gelfEncoder) AddUint32(k string, v uint32)   { enc.AddUint64(k, uint64(v)) }
+func (enc *gelfEncoder) AddUint16(k string, v uint16)   { enc.AddUint64(k, uint64(v)) }
+func (enc *gelfEncoder) AddUint8(k string, v uint8)     { enc.AddUint64(k, uint64(v)) }
+func (enc *gelfEncoder) AddUintptr(k string, v uintptr) { enc.AddUint64(k, uint64(v)) }
+
+func (enc *gelfEncoder) Clone() zapcore.Encoder {
+	clone := enc.clone()
+	clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *gelfEncoder) clone() *gelfEncoder {
+	return &gelfEncoder{jsonEncoder: enc.jsonEncoder.clone(), host: enc.host, prefix: enc.prefix}
+}
+
+func (enc *gelfEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+	final.buf.AppendByte('{')
+
+	final.addKey("version")
+	final.AppendString("1.1")
+	final.addKey("host")
+	final.AppendString(enc.host)
+	final.addKey("short_message")
+	final.AppendString(ent.Message)
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.addKey("full_message")
+		final.AppendString(ent.Message + "\n" + ent.Stack)
+	}
+	final.addKey("timestamp")
+	final.buf.AppendFloat(float64(ent.Time.UnixNano()/int64(time.Millisecond))/1000, 64)
+	final.addKey("level")
+	final.buf.AppendInt(int64(SyslogSeverity(ent.Level)))
+
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.addKey("_logger")
+		final.AppendString(ent.LoggerName)
+	}
+	if ent.Caller.Defined && final.CallerKey != "" {
+		final.addKey("_caller")
+		cur := final.buf.Len()
+		final.EncodeCaller(ent.Caller, final.jsonEncoder)
+		if cur == final.buf.Len() {
+			final.AppendString(ent.Caller.String())
+		}
+	}
+
+	if enc.buf.Len() > 0 {
+		final.addElementSeparator()
+		final.buf.Write(enc.buf.Bytes())
+	}
+	addFields(final, fields)
+	final.buf.AppendByte('}')
+	final.buf.AppendByte('\n')
+
+	ret := final.buf
+	putJSONEncoder(final.jsonEncoder)
+	return ret, nil
+}
@@ -0,0 +1,65 @@
+package zapwriter
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGELFEncoder(t *testing.T) {
+	hostname, _ := os.Hostname()
+
+	cfg := NewConfig()
+	cfg.Encoding = "gelf"
+
+	logger, buf := testConfigLogger(t, cfg)
+	logger.Named("access").With(zap.Int("id", 42)).Warn("message text",
+		zap.String("key", "value"),
+		zap.Bool("ok", true),
+		zap.Namespace("request"),
+		zap.Duration("time", time.Second),
+		zap.Strings("tags", []string{"a", "b"}),
+	)
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &msg); err != nil {
+		t.Fatal(err, buf.String())
+	}
+
+	expected := map[string]interface{}{
+		"version":       "1.1",
+		"host":          hostname,
+		"short_message": "message text",
+		"level":         float64(4),
+		"_logger":       "access",
+		"_id_":          float64(42),
+		"_key":          "value",
+		"_ok":           "true",
+		"_request_time": float64(1),
+		"_request_tags": `["a","b"]`,
+	}
+
+	for k, v := range expected {
+		if msg[k] != v {
+			t.Fatalf("%s: %#v != %#v (%s)", k, msg[k], v, buf.String())
+		}
+	}
+
+	if _, ok := msg["timestamp"].(float64); !ok {
+		t.Fatal(buf.String())
+	}
+
+	// same severity as syslog and journald outputs
+	enc := NewGELFEncoder(zap.NewProductionEncoderConfig())
+	out, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.PanicLevel, Time: time.Now()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(out.Bytes(), &msg); err != nil || msg["level"] != float64(1) {
+		t.Fatal(err, out.String())
+	}
+}
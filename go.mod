@@ -5,6 +5,8 @@ go 1.16
 require (
 	github.com/Shopify/sarama v1.29.0
 	github.com/eclipse/paho.mqtt.golang v1.3.5
+	github.com/golang/snappy v0.0.3
+	github.com/klauspost/compress v1.12.2
 	github.com/pkg/errors v0.9.1
 	go.uber.org/zap v1.17.0
 	golang.org/x/sys v0.0.0-20210616094352-59db8d763f22
@@ -0,0 +1,401 @@
+package httpbatch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lomik/zapwriter"
+	"go.uber.org/zap"
+)
+
+// Options of Batcher, parsed from DSN by ParseOptions
+type Options struct {
+	URL             string // endpoint
+	Headers         http.Header
+	ContentType     string
+	Gzip            bool
+	BatchSize       int
+	FlushInterval   time.Duration
+	QueueSize       int    // batches waiting for send
+	DropPolicy      string // "oldest", "newest" or "block"
+	Timeout         time.Duration
+	RetryMax        int
+	RetryBackoff    time.Duration
+	RetryBackoffMax time.Duration
+	ErrorLogger     string // fallback logger
+}
+
+// params used by Options, all other query params are passed to endpoint
+var optionParams = map[string]bool{
+	"content-type":      true,
+	"content-encoding":  true,
+	"batch-size":        true,
+	"flush-interval":    true,
+	"queue-size":        true,
+	"drop-policy":       true,
+	"timeout":           true,
+	"retry-max":         true,
+	"retry-backoff":     true,
+	"retry-backoff-max": true,
+	"error_logger":      true,
+}
+
+// ParseOptions parses common options of batching outputs. Endpoint is DSN with scheme replaced to given one and
+// without own params. Known are own params of output (or prefixes like "label."), they are not passed to endpoint too.
+//
+// DSN params:
+//
+//	batch-size=1000           max items in one request
+//	flush-interval=1s         send incomplete batch after this interval
+//	content-encoding=gzip     compress request body
+//	content-type=...          Content-Type of request
+//	header.<Name>=value       additional request header
+//	queue-size=16             batches waiting for send
+//	drop-policy=oldest        what to do with full queue: drop "oldest" or "newest" batch, or "block" writes
+//	timeout=10s               request timeout
+//	retry-max=5               retries of failed request (network errors, 429 and 5xx responses)
+//	retry-backoff=500ms       first retry delay, doubled on every retry
+//	retry-backoff-max=30s     max retry delay
+//	error_logger=name         logger for send errors
+func ParseOptions(u *url.URL, scheme string, known ...string) (Options, error) {
+	params := zapwriter.DSN(u.Query())
+
+	opts := Options{
+		Headers:         make(http.Header),
+		BatchSize:       1000,
+		FlushInterval:   time.Second,
+		QueueSize:       16,
+		DropPolicy:      "oldest",
+		Timeout:         10 * time.Second,
+		RetryMax:        5,
+		RetryBackoff:    500 * time.Millisecond,
+		RetryBackoffMax: 30 * time.Second,
+	}
+
+	if err := zapwriter.AnyError(
+		params.SetString(&opts.ContentType, "content-type"),
+		params.SetInt(&opts.BatchSize, "batch-size"),
+		params.SetDuration(&opts.FlushInterval, "flush-interval"),
+		params.SetInt(&opts.QueueSize, "queue-size"),
+		params.SetString(&opts.DropPolicy, "drop-policy"),
+		params.SetDuration(&opts.Timeout, "timeout"),
+		params.SetInt(&opts.RetryMax, "retry-max"),
+		params.SetDuration(&opts.RetryBackoff, "retry-backoff"),
+		params.SetDuration(&opts.RetryBackoffMax, "retry-backoff-max"),
+		params.SetString(&opts.ErrorLogger, "error_logger"),
+	); err != nil {
+		return opts, err
+	}
+
+	switch params.Get("content-encoding") {
+	case "":
+	case "gzip":
+		opts.Gzip = true
+	default:
+		return opts, fmt.Errorf("unknown content-encoding %#v", params.Get("content-encoding"))
+	}
+
+	if opts.BatchSize < 1 || opts.QueueSize < 1 || opts.FlushInterval <= 0 {
+		return opts, fmt.Errorf("batch-size, queue-size and flush-interval must be positive")
+	}
+
+	if opts.DropPolicy != "oldest" && opts.DropPolicy != "newest" && opts.DropPolicy != "block" {
+		return opts, fmt.Errorf("unknown drop-policy %#v", opts.DropPolicy)
+	}
+
+	skip := make(map[string]bool)
+	for _, k := range known {
+		skip[k] = true
+	}
+
+	query := url.Values{}
+	for key, values := range u.Query() {
+		if strings.HasPrefix(key, "header.") {
+			for _, v := range values {
+				opts.Headers.Add(strings.TrimPrefix(key, "header."), v)
+			}
+			continue
+		}
+		if optionParams[key] || skip[key] || skip[key[:strings.Index(key, ".")+1]] {
+			continue
+		}
+		query[key] = values
+	}
+
+	endpoint := *u
+	endpoint.Scheme = scheme
+	endpoint.RawQuery = query.Encode()
+	opts.URL = endpoint.String()
+
+	return opts, nil
+}
+
+type batch struct {
+	items []interface{}
+	done  chan struct{}
+	err   error
+}
+
+// Batcher collects items and sends them in batches by POST requests. Batches are sent one by one in order
+type Batcher struct {
+	opts   Options
+	client *http.Client
+	encode func(items []interface{}) ([]byte, error)
+	check  func(resp *http.Response, body []byte, items []interface{}) error
+
+	mu    sync.Mutex
+	items []interface{}
+	last  *batch // last batch passed to sender
+
+	dropped int64 // items of batches dropped because of full queue
+
+	batches  chan *batch
+	exit     chan interface{}
+	exitOnce sync.Once
+	exitWg   sync.WaitGroup
+}
+
+// NewBatcher creates batcher. encode makes request body of batch items.
+// check validates response with 2xx status, can be nil
+func NewBatcher(opts Options, encode func(items []interface{}) ([]byte, error), check func(resp *http.Response, body []byte, items []interface{}) error) *Batcher {
+	b := &Batcher{
+		opts:    opts,
+		client:  &http.Client{Timeout: opts.Timeout},
+		encode:  encode,
+		check:   check,
+		batches: make(chan *batch, opts.QueueSize),
+		exit:    make(chan interface{}),
+	}
+
+	b.exitWg.Add(2)
+	go func() {
+		b.sender(b.exit)
+		b.exitWg.Done()
+	}()
+	go func() {
+		b.flusher(b.exit)
+		b.exitWg.Done()
+	}()
+
+	return b
+}
+
+// Add appends item to current batch. Batch is sent when it is full or after flush-interval
+func (b *Batcher) Add(item interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.items = append(b.items, item)
+	if len(b.items) >= b.opts.BatchSize {
+		return b.flushLocked()
+	}
+	return nil
+}
+
+// flushLocked passes current batch to sender. Must be called under lock
+func (b *Batcher) flushLocked() error {
+	if len(b.items) == 0 {
+		return nil
+	}
+
+	bt := &batch{items: b.items, done: make(chan struct{})}
+	b.items = nil
+
+	switch b.opts.DropPolicy {
+	case "block":
+		select {
+		case b.batches <- bt:
+		case <-b.exit:
+			return fmt.Errorf("aborted")
+		}
+	case "newest":
+		select {
+		case b.batches <- bt:
+		default:
+			b.drop(bt)
+			return bt.err
+		}
+	default: // oldest
+	enqueue:
+		for {
+			select {
+			case b.batches <- bt:
+				break enqueue
+			default:
+			}
+
+			select {
+			case old := <-b.batches:
+				b.drop(old)
+			default:
+			}
+		}
+	}
+
+	b.last = bt
+	return nil
+}
+
+// drop completes batch which was not sent because of full queue
+func (b *Batcher) drop(bt *batch) {
+	atomic.AddInt64(&b.dropped, int64(len(bt.items)))
+	bt.err = fmt.Errorf("queue is full, %d items dropped", len(bt.items))
+	close(bt.done)
+}
+
+// Dropped returns number of items dropped because of full queue
+func (b *Batcher) Dropped() int64 {
+	return atomic.LoadInt64(&b.dropped)
+}
+
+func (b *Batcher) flusher(exit chan interface{}) {
+	ticker := time.NewTicker(b.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// dropped batch is reported by Sync, abort is handled by exit
+			b.mu.Lock()
+			b.flushLocked()
+			b.mu.Unlock()
+		case <-exit:
+			return
+		}
+	}
+}
+
+func (b *Batcher) sender(exit chan interface{}) {
+	for {
+		select {
+		case bt := <-b.batches:
+			bt.err = b.send(bt.items, exit)
+			if bt.err != nil && b.opts.ErrorLogger != "" {
+				zapwriter.Logger(b.opts.ErrorLogger).Error("send batch failed",
+					zap.String("url", b.opts.URL),
+					zap.Int("items", len(bt.items)),
+					zap.Error(bt.err),
+				)
+			}
+			close(bt.done)
+		case <-exit:
+			return
+		}
+	}
+}
+
+func (b *Batcher) send(items []interface{}, exit chan interface{}) error {
+	body, err := b.encode(items)
+	if err != nil {
+		return err
+	}
+
+	if b.opts.Gzip {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(body)
+		w.Close()
+		body = buf.Bytes()
+	}
+
+	backoff := b.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		if retry, err = b.post(body, items); err == nil || !retry || attempt >= b.opts.RetryMax {
+			return err
+		}
+
+		if b.opts.ErrorLogger != "" {
+			zapwriter.Logger(b.opts.ErrorLogger).Warn("send batch failed, retrying", zap.String("url", b.opts.URL), zap.Error(err))
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-exit:
+			return err
+		}
+
+		backoff *= 2
+		if backoff > b.opts.RetryBackoffMax {
+			backoff = b.opts.RetryBackoffMax
+		}
+	}
+}
+
+// post sends body, returns whether failed request should be retried
+func (b *Batcher) post(body []byte, items []interface{}) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, b.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	for k, v := range b.opts.Headers {
+		req.Header[k] = v
+	}
+	if b.opts.ContentType != "" {
+		req.Header.Set("Content-Type", b.opts.ContentType)
+	}
+	if b.opts.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+	}
+
+	if b.check != nil {
+		return false, b.check(resp, respBody, items)
+	}
+
+	return false, nil
+}
+
+// Sync sends current batch and waits until all batches are acknowledged.
+// Returns error of last batch
+func (b *Batcher) Sync() error {
+	b.mu.Lock()
+	err := b.flushLocked()
+	last := b.last
+	b.mu.Unlock()
+
+	if err != nil || last == nil {
+		return err
+	}
+
+	select {
+	case <-last.done:
+		return last.err
+	case <-b.exit:
+		return fmt.Errorf("aborted")
+	}
+}
+
+// Close sends pending items and stops batcher
+func (b *Batcher) Close() (err error) {
+	err = b.Sync()
+	b.exitOnce.Do(func() {
+		close(b.exit)
+	})
+	b.exitWg.Wait()
+	return
+}
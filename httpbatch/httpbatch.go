@@ -0,0 +1,64 @@
+package httpbatch
+
+import (
+	"bytes"
+	"net/url"
+
+	"github.com/lomik/zapwriter"
+)
+
+func init() {
+	zapwriter.RegisterScheme("http", New)
+	zapwriter.RegisterScheme("https", New)
+}
+
+// HTTPOutput posts batches of newline-delimited encoded messages.
+//
+// DSN example:
+//
+//	https://collector:8080/ingest?batch-size=500&flush-interval=2s&content-encoding=gzip&header.Authorization=Bearer+token
+//
+// See ParseOptions for all params
+type HTTPOutput struct {
+	*Batcher
+}
+
+func New(path string) (zapwriter.Output, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := ParseOptions(u, u.Scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ContentType == "" {
+		opts.ContentType = "application/x-ndjson"
+	}
+
+	return &HTTPOutput{NewBatcher(opts, encodeLines, nil)}, nil
+}
+
+func encodeLines(items []interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, item := range items {
+		line := item.([]byte)
+		buf.Write(line)
+		if len(line) == 0 || line[len(line)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (h *HTTPOutput) Write(p []byte) (int, error) {
+	// data race fix
+	m := make([]byte, len(p))
+	copy(m, p)
+	if err := h.Add(m); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
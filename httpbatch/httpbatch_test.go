@@ -0,0 +1,113 @@
+package httpbatch
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHTTPOutput(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	var calls int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.Header.Get("Content-Encoding") != "gzip" || r.Header.Get("X-Token") != "secret" || r.URL.RawQuery != "source=app" {
+			t.Errorf("unexpected request %#v", r)
+		}
+
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		body, _ := ioutil.ReadAll(gz)
+		bodies = append(bodies, string(body))
+	}))
+	defer srv.Close()
+
+	out, err := New(srv.URL + "/ingest?source=app&batch-size=2&flush-interval=1h&content-encoding=gzip&header.X-Token=secret&retry-backoff=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.(*HTTPOutput).Close()
+
+	out.Write([]byte("first\n"))
+	out.Write([]byte("second\n"))
+	out.Write([]byte("third\n"))
+
+	if err := out.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if calls != 3 || len(bodies) != 2 || bodies[0] != "first\nsecond\n" || bodies[1] != "third\n" {
+		t.Fatalf("%d %#v", calls, bodies)
+	}
+}
+
+func TestHTTPOutputClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	out, err := New(srv.URL + "?retry-backoff=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.(*HTTPOutput).Close()
+
+	out.Write([]byte("message\n"))
+
+	if err := out.Sync(); err == nil {
+		t.Fatal("error expected")
+	}
+}
+
+func TestHTTPOutputDropOldest(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	out, err := New(srv.URL + "?batch-size=1&queue-size=1&retry-max=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := out.(*HTTPOutput)
+	defer h.Close()
+	defer close(release)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if _, err := out.Write([]byte("message\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("writes blocked for %s", d)
+	}
+
+	// one batch is sent, one is queued
+	if h.Dropped() < 8 {
+		t.Fatalf("dropped %d", h.Dropped())
+	}
+}
@@ -0,0 +1,277 @@
+// +build linux
+
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/lomik/zapwriter"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultSocket = "/run/systemd/journal/socket"
+
+func init() {
+	zapwriter.RegisterCoreScheme("journald", NewCore)
+}
+
+// NewCore creates core which sends entries with fields to journal
+func NewCore(dsn string, enc zapcore.Encoder, lvl zap.AtomicLevel) (zapcore.Core, error) {
+	out, err := New(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return zapwriter.NewOutputCore(enc, lvl, out), nil
+}
+
+// JournaldOutput writes entries to journal with native protocol.
+// Zap fields are sent as uppercased journal fields.
+//
+// DSN examples:
+//
+//	journald://
+//	journald:///run/systemd/journal/socket?identifier=app&message=encoded
+type JournaldOutput struct {
+	sync.Mutex
+	socket     string
+	identifier string
+	encoded    bool // send encoded entry as MESSAGE instead of entry message
+	conn       *net.UnixConn
+}
+
+func New(path string) (zapwriter.Output, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	params := zapwriter.DSN(u.Query())
+
+	socket := u.Path
+	if socket == "" || socket == "/" {
+		socket = defaultSocket
+	}
+
+	identifier, err := params.String("identifier", filepath.Base(os.Args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := params.String("message", "entry")
+	if err != nil {
+		return nil, err
+	}
+	if message != "entry" && message != "encoded" {
+		return nil, fmt.Errorf("unknown message %#v", message)
+	}
+
+	return &JournaldOutput{
+		socket:     socket,
+		identifier: identifier,
+		encoded:    message == "encoded",
+	}, nil
+}
+
+// FieldName converts zap field key to journal field name: uppercase letters, digits and underscores,
+// not starting with underscore or digit
+func FieldName(key string) string {
+	b := make([]byte, 0, len(key))
+	for _, c := range []byte(strings.ToUpper(key)) {
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			b = append(b, c)
+		} else {
+			b = append(b, '_')
+		}
+	}
+
+	name := strings.TrimLeft(string(b), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "F_" + name
+	}
+	return name
+}
+
+func appendField(buf *bytes.Buffer, name string, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	// KEY\n<64-bit little endian size><value>\n
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// flatten adds nested maps (namespaces, objects) with "_" joined names
+func flatten(res map[string]string, prefix string, m map[string]interface{}) {
+	for k, v := range m {
+		name := k
+		if prefix != "" {
+			name = prefix + "_" + k
+		}
+
+		switch value := v.(type) {
+		case map[string]interface{}:
+			flatten(res, name, value)
+		case string:
+			res[FieldName(name)] = value
+		case []byte:
+			res[FieldName(name)] = string(value)
+		case error:
+			res[FieldName(name)] = value.Error()
+		case fmt.Stringer:
+			res[FieldName(name)] = value.String()
+		case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64:
+			res[FieldName(name)] = fmt.Sprint(value)
+		default:
+			if b, err := json.Marshal(value); err == nil {
+				res[FieldName(name)] = string(b)
+			} else {
+				res[FieldName(name)] = fmt.Sprint(value)
+			}
+		}
+	}
+}
+
+func (j *JournaldOutput) message(ent zapcore.Entry, fields []zapcore.Field, p []byte) []byte {
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	values := make(map[string]string)
+	flatten(values, "", enc.Fields)
+
+	message := ent.Message
+	if j.encoded {
+		message = string(bytes.TrimRight(p, "\n"))
+	}
+
+	values["MESSAGE"] = message
+	values["PRIORITY"] = strconv.Itoa(zapwriter.SyslogSeverity(ent.Level))
+	values["SYSLOG_IDENTIFIER"] = j.identifier
+	if ent.LoggerName != "" {
+		values["LOGGER"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		values["CODE_FILE"] = ent.Caller.File
+		values["CODE_LINE"] = strconv.Itoa(ent.Caller.Line)
+		if ent.Caller.Function != "" {
+			values["CODE_FUNC"] = ent.Caller.Function
+		}
+	}
+	if ent.Stack != "" {
+		values["STACKTRACE"] = ent.Stack
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		appendField(&buf, name, values[name])
+	}
+	return buf.Bytes()
+}
+
+func (j *JournaldOutput) send(msg []byte) error {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.conn == nil {
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: j.socket, Net: "unixgram"})
+		if err != nil {
+			return err
+		}
+		j.conn = conn
+	}
+
+	_, err := j.conn.Write(msg)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		j.conn.Close()
+		j.conn = nil
+		return err
+	}
+
+	// too large for datagram: pass data in unlinked temporary file
+	return j.sendFile(msg)
+}
+
+func (j *JournaldOutput) sendFile(msg []byte) error {
+	f, err := ioutil.TempFile("/dev/shm", "journal.")
+	if err != nil {
+		if f, err = ioutil.TempFile("", "journal."); err != nil {
+			return err
+		}
+	}
+	defer f.Close()
+
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+
+	if _, err := f.Write(msg); err != nil {
+		return err
+	}
+
+	_, _, err = j.conn.WriteMsgUnix([]byte{}, syscall.UnixRights(int(f.Fd())), nil)
+	return err
+}
+
+func (j *JournaldOutput) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) (int, error) {
+	if err := j.send(j.message(ent, fields, p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Write sends encoded message with info priority if entry is unknown
+func (j *JournaldOutput) Write(p []byte) (int, error) {
+	msg := j.message(zapcore.Entry{Level: zapcore.InfoLevel, Message: string(bytes.TrimRight(p, "\n"))}, nil, p)
+	if err := j.send(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (j *JournaldOutput) Sync() error {
+	return nil
+}
+
+func (j *JournaldOutput) Close() (err error) {
+	j.Lock()
+	if j.conn != nil {
+		err = j.conn.Close()
+		j.conn = nil
+	}
+	j.Unlock()
+	return
+}
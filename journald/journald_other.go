@@ -0,0 +1,29 @@
+// +build !linux
+
+package journald
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/lomik/zapwriter"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// journald is linux only, scheme is registered to report clear error
+func init() {
+	zapwriter.RegisterCoreScheme("journald", NewCore)
+}
+
+func NewCore(dsn string, enc zapcore.Encoder, lvl zap.AtomicLevel) (zapcore.Core, error) {
+	return nil, errUnsupported()
+}
+
+func New(path string) (zapwriter.Output, error) {
+	return nil, errUnsupported()
+}
+
+func errUnsupported() error {
+	return fmt.Errorf("journald is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,74 @@
+// +build linux
+
+package journald
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lomik/zapwriter"
+	"go.uber.org/zap"
+)
+
+func TestJournald(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sock := filepath.Join(dir, "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	cfg := zapwriter.NewConfig()
+	cfg.File = fmt.Sprintf("journald://%s?identifier=test", sock)
+	cfg.Caller = true
+
+	logger, err := cfg.BuildLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Named("access").With(zap.String("request-id", "42")).Error("message text",
+		zap.Int("status", 500),
+		zap.Namespace("http"),
+		zap.String("body", "a\nb"),
+	)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := string(buf[:n])
+	for _, expected := range []string{
+		"MESSAGE=message text\n",
+		"PRIORITY=3\n",
+		"SYSLOG_IDENTIFIER=test\n",
+		"LOGGER=access\n",
+		"REQUEST_ID=42\n",
+		"STATUS=500\n",
+		"HTTP_BODY\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n",
+		"CODE_LINE=",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Fatalf("%#v not found in %#v", expected, msg)
+		}
+	}
+
+	if !strings.Contains(msg, "CODE_FILE=") || !strings.Contains(msg, "journald_test.go") {
+		t.Fatal(msg)
+	}
+}
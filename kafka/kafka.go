@@ -10,16 +10,20 @@ import (
 	"github.com/Shopify/sarama"
 	"github.com/lomik/zapwriter"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func init() {
 	zapwriter.RegisterScheme("kafka", New)
 }
 
+// KafkaOutput sends entries to topic of entry level, it is zapwriter.EntryWriter:
+// kafka://host:9092/?topic=logs&topic.error=errors&topic.fatal=errors
 type KafkaOutput struct {
 	sync.RWMutex
 	addrs         []string
 	topic         string
+	levelTopics   map[zapcore.Level]string // topic.<level> overrides topic
 	sync          bool
 	asyncProducer sarama.AsyncProducer
 	syncProducer  sarama.SyncProducer
@@ -116,6 +120,18 @@ func New(path string) (zapwriter.Output, error) {
 		return nil, err
 	}
 
+	levelTopics := make(map[zapcore.Level]string)
+	for key := range params.Values {
+		if !strings.HasPrefix(key, "topic.") {
+			continue
+		}
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(strings.TrimPrefix(key, "topic."))); err != nil {
+			return nil, err
+		}
+		levelTopics[level] = params.Get(key)
+	}
+
 	errorLogger, err := params.String("error_logger", "")
 	if err != nil {
 		return nil, err
@@ -126,7 +142,9 @@ func New(path string) (zapwriter.Output, error) {
 		addrs:       strings.Split(u.Host, ","),
 		sync:        sync,
 		topic:       topic,
+		levelTopics: levelTopics,
 		errorLogger: errorLogger,
+		exit:        make(chan interface{}),
 	}
 
 	return r, nil
@@ -198,7 +216,7 @@ func (r *KafkaOutput) getAsyncProducer() (sarama.AsyncProducer, error) {
 	return p, nil
 }
 
-func (r *KafkaOutput) writeSync(p []byte) (int, error) {
+func (r *KafkaOutput) writeSync(topic string, p []byte) (int, error) {
 	for {
 		select {
 		case <-r.exit:
@@ -214,7 +232,7 @@ func (r *KafkaOutput) writeSync(p []byte) (int, error) {
 		}
 
 		_, _, err = producer.SendMessage(&sarama.ProducerMessage{
-			Topic:     r.topic,
+			Topic:     topic,
 			Key:       sarama.StringEncoder(""),
 			Value:     sarama.ByteEncoder(p),
 			Timestamp: time.Now(),
@@ -230,14 +248,14 @@ func (r *KafkaOutput) writeSync(p []byte) (int, error) {
 	}
 }
 
-func (r *KafkaOutput) writeAsync(p []byte) (int, error) {
+func (r *KafkaOutput) writeAsync(topic string, p []byte) (int, error) {
 	producer, err := r.getAsyncProducer()
 	if err != nil {
 		return 0, err
 	}
 
 	msg := &sarama.ProducerMessage{
-		Topic:     r.topic,
+		Topic:     topic,
 		Key:       sarama.StringEncoder(""),
 		Value:     sarama.ByteEncoder(p),
 		Timestamp: time.Now(),
@@ -252,13 +270,26 @@ func (r *KafkaOutput) writeAsync(p []byte) (int, error) {
 }
 
 func (r *KafkaOutput) Write(p []byte) (n int, err error) {
+	return r.write(r.topic, p)
+}
+
+// WriteEntry sends message to topic of entry level
+func (r *KafkaOutput) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) (n int, err error) {
+	topic, ok := r.levelTopics[ent.Level]
+	if !ok {
+		topic = r.topic
+	}
+	return r.write(topic, p)
+}
+
+func (r *KafkaOutput) write(topic string, p []byte) (n int, err error) {
 	// data race fix
 	m := make([]byte, len(p))
 	copy(m, p)
 	if r.sync {
-		n, err = r.writeSync(m)
+		n, err = r.writeSync(topic, m)
 	} else {
-		n, err = r.writeAsync(m)
+		n, err = r.writeAsync(topic, m)
 	}
 
 	if err != nil && r.errorLogger != "" {
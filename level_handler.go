@@ -0,0 +1,86 @@
+package zapwriter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelHandler returns http.Handler of global manager levels, like zap.AtomicLevel.ServeHTTP but for all loggers.
+//
+// GET / returns levels of all loggers: {"levels": {"<logger>": {"<output>": "info"}}}.
+// GET /?logger=<name> returns levels of one logger: {"logger": "<name>", "levels": {"<output>": "info"}}.
+// PUT /?logger=<name> with body {"level": "debug"} changes level of all outputs of logger.
+func LevelHandler() http.Handler {
+	return levelHandler{}
+}
+
+type levelHandler struct{}
+
+type levelsResponse struct {
+	Levels map[string]map[string]zapcore.Level `json:"levels"`
+}
+
+type loggerLevelsResponse struct {
+	Logger string                   `json:"logger"`
+	Levels map[string]zapcore.Level `json:"levels"`
+}
+
+type levelErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+
+	fail := func(code int, err error) {
+		w.WriteHeader(code)
+		enc.Encode(levelErrorResponse{Error: err.Error()})
+	}
+
+	logger, hasLogger := r.URL.Query()["logger"]
+
+	switch r.Method {
+	case http.MethodGet:
+		if !hasLogger {
+			enc.Encode(levelsResponse{Levels: Levels()})
+			return
+		}
+	case http.MethodPut:
+		if !hasLogger {
+			fail(http.StatusBadRequest, fmt.Errorf("logger parameter is required"))
+			return
+		}
+
+		var req struct {
+			Level *zapcore.Level `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			fail(http.StatusBadRequest, err)
+			return
+		}
+		if req.Level == nil {
+			fail(http.StatusBadRequest, fmt.Errorf("must specify a logging level"))
+			return
+		}
+
+		if err := SetLevel(logger[0], *req.Level); err != nil {
+			fail(http.StatusNotFound, err)
+			return
+		}
+	default:
+		fail(http.StatusMethodNotAllowed, fmt.Errorf("only GET and PUT are supported"))
+		return
+	}
+
+	levels, ok := Levels()[logger[0]]
+	if !ok {
+		fail(http.StatusNotFound, fmt.Errorf("unknown logger name %#v", logger[0]))
+		return
+	}
+
+	enc.Encode(loggerLevelsResponse{Logger: logger[0], Levels: levels})
+}
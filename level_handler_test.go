@@ -0,0 +1,65 @@
+package zapwriter
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelHandler(t *testing.T) {
+	setCloseGracePeriod(t, 0)
+
+	cfg := NewConfig()
+	cfg.Logger = "access"
+	cfg.File = "stdout"
+
+	if err := ApplyConfig([]Config{NewConfig(), cfg}); err != nil {
+		t.Fatal(err)
+	}
+	defer ApplyConfig([]Config{NewConfig()})
+
+	srv := httptest.NewServer(LevelHandler())
+	defer srv.Close()
+
+	request := func(method, query, body string) (int, string) {
+		req, err := http.NewRequest(method, srv.URL+query, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(b))
+	}
+
+	table := []struct {
+		method, query, body string
+		code                int
+		response            string
+	}{
+		{"GET", "/", "", 200, `{"levels":{"":{"stderr":"info"},"access":{"stdout":"info"}}}`},
+		{"PUT", "/?logger=access", `{"level":"debug"}`, 200, `{"logger":"access","levels":{"stdout":"debug"}}`},
+		{"GET", "/?logger=access", "", 200, `{"logger":"access","levels":{"stdout":"debug"}}`},
+		{"PUT", "/?logger=unknown", `{"level":"debug"}`, 404, `{"error":"unknown logger name \"unknown\""}`},
+		{"PUT", "/?logger=access", `{"level":"verbose"}`, 400, `{"error":"unrecognized level: \"verbose\""}`},
+		{"POST", "/", "", 405, `{"error":"only GET and PUT are supported"}`},
+	}
+
+	for _, test := range table {
+		code, response := request(test.method, test.query, test.body)
+		if code != test.code || response != test.response {
+			t.Fatalf("%s %s: %d %s", test.method, test.query, code, response)
+		}
+	}
+
+	if !Logger("access").Core().Enabled(zapcore.DebugLevel) {
+		t.Fatal("level not changed")
+	}
+}
@@ -0,0 +1,297 @@
+package zapwriter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// logfmtEncoder writes entries as key=value pairs. Nested objects and namespaces
+// are flattened with dotted keys, arrays are joined with comma
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf    *buffer.Buffer
+	prefix string // key prefix of current namespace
+}
+
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{EncoderConfig: &cfg, buf: bufferpool.Get()}
+}
+
+func logfmtNeedsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func logfmtKeyChar(r rune) rune {
+	if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+		return '_'
+	}
+	return r
+}
+
+func (enc *logfmtEncoder) addKey(key string) {
+	if enc.buf.Len() > 0 {
+		enc.buf.AppendByte(' ')
+	}
+	key = strings.Map(logfmtKeyChar, enc.prefix+key)
+	if key == "" {
+		key = "_"
+	}
+	enc.buf.AppendString(key)
+	enc.buf.AppendByte('=')
+}
+
+func (enc *logfmtEncoder) appendValue(val string) {
+	if logfmtNeedsQuote(val) {
+		enc.buf.AppendString(strconv.Quote(val))
+	} else {
+		enc.buf.AppendString(val)
+	}
+}
+
+// addEncoded adds value produced by one of EncoderConfig callbacks, fallback is used if callback is no-op
+func (enc *logfmtEncoder) addEncoded(key string, encode func(zapcore.PrimitiveArrayEncoder), fallback string) {
+	arr := &logfmtArrayEncoder{cfg: enc.EncoderConfig}
+	encode(arr)
+	enc.addKey(key)
+	if len(arr.elems) == 0 {
+		enc.appendValue(fallback)
+		return
+	}
+	enc.appendValue(strings.Join(arr.elems, ","))
+}
+
+func (enc *logfmtEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	arr := &logfmtArrayEncoder{cfg: enc.EncoderConfig}
+	err := marshaler.MarshalLogArray(arr)
+	enc.addKey(key)
+	enc.appendValue(strings.Join(arr.elems, ","))
+	return err
+}
+
+func (enc *logfmtEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	return obj.MarshalLogObject(&logfmtEncoder{EncoderConfig: enc.EncoderConfig, buf: enc.buf, prefix: enc.prefix + key + "."})
+}
+
+func (enc *logfmtEncoder) AddBinary(key string, val []byte) {
+	enc.AddString(key, base64.StdEncoding.EncodeToString(val))
+}
+
+func (enc *logfmtEncoder) AddByteString(key string, val []byte) {
+	enc.AddString(key, string(val))
+}
+
+func (enc *logfmtEncoder) AddBool(key string, val bool) {
+	enc.addKey(key)
+	enc.buf.AppendBool(val)
+}
+
+func (enc *logfmtEncoder) AddComplex128(key string, val complex128) {
+	enc.addKey(key)
+	enc.appendValue(strconv.FormatComplex(val, 'f', -1, 128))
+}
+
+func (enc *logfmtEncoder) AddDuration(key string, val time.Duration) {
+	if enc.EncodeDuration == nil {
+		enc.AddInt64(key, int64(val))
+		return
+	}
+	enc.addEncoded(key, func(arr zapcore.PrimitiveArrayEncoder) { enc.EncodeDuration(val, arr) }, strconv.FormatInt(int64(val), 10))
+}
+
+func (enc *logfmtEncoder) AddFloat64(key string, val float64) {
+	enc.addKey(key)
+	enc.buf.AppendFloat(val, 64)
+}
+
+func (enc *logfmtEncoder) AddInt64(key string, val int64) {
+	enc.addKey(key)
+	enc.buf.AppendInt(val)
+}
+
+func (enc *logfmtEncoder) AddReflected(key string, obj interface{}) error {
+	marshaled, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	enc.AddString(key, string(marshaled))
+	return nil
+}
+
+func (enc *logfmtEncoder) OpenNamespace(key string) {
+	enc.prefix = enc.prefix + key + "."
+}
+
+func (enc *logfmtEncoder) AddString(key, val string) {
+	enc.addKey(key)
+	enc.appendValue(val)
+}
+
+func (enc *logfmtEncoder) AddTime(key string, val time.Time) {
+	if enc.EncodeTime == nil {
+		enc.AddInt64(key, val.UnixNano())
+		return
+	}
+	enc.addEncoded(key, func(arr zapcore.PrimitiveArrayEncoder) { enc.EncodeTime(val, arr) }, strconv.FormatInt(val.UnixNano(), 10))
+}
+
+func (enc *logfmtEncoder) AddUint64(key string, val uint64) {
+	enc.addKey(key)
+	enc.buf.AppendUint(val)
+}
+
+func (enc *logfmtEncoder) AddComplex64(k string, v complex64) { enc.AddComplex128(k, complex128(v)) }
+func (enc *logfmtEncoder) AddFloat32(k string, v float32)     { enc.AddFloat64(k, float64(v)) }
+func (enc *logfmtEncoder) AddInt(k string, v int)             { enc.AddInt64(k, int64(v)) }
+func (enc *logfmtEncoder) AddInt32(k string, v int32)         { enc.AddInt64(k, int64(v)) }
+func (enc *logfmtEncoder) AddInt16(k string, v int16)         { enc.AddInt64(k, int64(v)) }
+func (enc *logfmtEncoder) AddInt8(k string, v int8)           { enc.AddInt64(k, int64(v)) }
+func (enc *logfmtEncoder) AddUint(k string, v uint)           { enc.AddUint64(k, uint64(v)) }
+func (enc *logfmtEncoder) AddUint32(k string, v uint32)       { enc.AddUint64(k, uint64(v)) }
+func (enc *logfmtEncoder) AddUint16(k string, v uint16)       { enc.AddUint64(k, uint64(v)) }
+func (enc *logfmtEncoder) AddUint8(k string, v uint8)         { enc.AddUint64(k, uint64(v)) }
+func (enc *logfmtEncoder) AddUintptr(k string, v uintptr)     { enc.AddUint64(k, uint64(v)) }
+
+func (enc *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := &logfmtEncoder{EncoderConfig: enc.EncoderConfig, buf: bufferpool.Get(), prefix: enc.prefix}
+	clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := &logfmtEncoder{EncoderConfig: enc.EncoderConfig, buf: bufferpool.Get()}
+
+	if final.TimeKey != "" {
+		final.AddTime(final.TimeKey, ent.Time)
+	}
+	if final.LevelKey != "" && final.EncodeLevel != nil {
+		final.addEncoded(final.LevelKey, func(arr zapcore.PrimitiveArrayEncoder) { final.EncodeLevel(ent.Level, arr) }, ent.Level.String())
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		if final.EncodeName != nil {
+			final.addEncoded(final.NameKey, func(arr zapcore.PrimitiveArrayEncoder) { final.EncodeName(ent.LoggerName, arr) }, ent.LoggerName)
+		} else {
+			final.AddString(final.NameKey, ent.LoggerName)
+		}
+	}
+	if ent.Caller.Defined {
+		if final.CallerKey != "" && final.EncodeCaller != nil {
+			final.addEncoded(final.CallerKey, func(arr zapcore.PrimitiveArrayEncoder) { final.EncodeCaller(ent.Caller, arr) }, ent.Caller.String())
+		}
+		if final.FunctionKey != "" && ent.Caller.Function != "" {
+			final.AddString(final.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if final.MessageKey != "" {
+		final.AddString(final.MessageKey, ent.Message)
+	}
+
+	if enc.buf.Len() > 0 {
+		if final.buf.Len() > 0 {
+			final.buf.AppendByte(' ')
+		}
+		final.buf.Write(enc.buf.Bytes())
+	}
+
+	final.prefix = enc.prefix
+	addFields(final, fields)
+
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.prefix = ""
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
+
+	if final.LineEnding != "" {
+		final.buf.AppendString(final.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+
+	return final.buf, nil
+}
+
+// logfmtArrayEncoder collects array elements and values of EncoderConfig callbacks as strings
+type logfmtArrayEncoder struct {
+	cfg   *zapcore.EncoderConfig
+	elems []string
+}
+
+func (arr *logfmtArrayEncoder) AppendArray(v zapcore.ArrayMarshaler) error {
+	nested := &logfmtArrayEncoder{cfg: arr.cfg}
+	err := v.MarshalLogArray(nested)
+	arr.AppendString("[" + strings.Join(nested.elems, ",") + "]")
+	return err
+}
+
+func (arr *logfmtArrayEncoder) AppendObject(v zapcore.ObjectMarshaler) error {
+	nested := &logfmtEncoder{EncoderConfig: arr.cfg, buf: bufferpool.Get()}
+	err := v.MarshalLogObject(nested)
+	arr.AppendString("{" + nested.buf.String() + "}")
+	nested.buf.Free()
+	return err
+}
+
+func (arr *logfmtArrayEncoder) AppendReflected(v interface{}) error {
+	marshaled, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	arr.AppendString(string(marshaled))
+	return nil
+}
+
+func (arr *logfmtArrayEncoder) AppendDuration(v time.Duration) {
+	if arr.cfg.EncodeDuration == nil {
+		arr.AppendInt64(int64(v))
+		return
+	}
+	arr.cfg.EncodeDuration(v, arr)
+}
+
+func (arr *logfmtArrayEncoder) AppendTime(v time.Time) {
+	if arr.cfg.EncodeTime == nil {
+		arr.AppendInt64(v.UnixNano())
+		return
+	}
+	arr.cfg.EncodeTime(v, arr)
+}
+
+func (arr *logfmtArrayEncoder) AppendString(v string)     { arr.elems = append(arr.elems, v) }
+func (arr *logfmtArrayEncoder) AppendBool(v bool)         { arr.AppendString(strconv.FormatBool(v)) }
+func (arr *logfmtArrayEncoder) AppendByteString(v []byte) { arr.AppendString(string(v)) }
+func (arr *logfmtArrayEncoder) AppendComplex128(v complex128) {
+	arr.AppendString(strconv.FormatComplex(v, 'f', -1, 128))
+}
+func (arr *logfmtArrayEncoder) AppendComplex64(v complex64) { arr.AppendComplex128(complex128(v)) }
+func (arr *logfmtArrayEncoder) AppendFloat64(v float64) {
+	arr.AppendString(strconv.FormatFloat(v, 'f', -1, 64))
+}
+func (arr *logfmtArrayEncoder) AppendFloat32(v float32) {
+	arr.AppendString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+}
+func (arr *logfmtArrayEncoder) AppendInt64(v int64)     { arr.AppendString(strconv.FormatInt(v, 10)) }
+func (arr *logfmtArrayEncoder) AppendInt(v int)         { arr.AppendInt64(int64(v)) }
+func (arr *logfmtArrayEncoder) AppendInt32(v int32)     { arr.AppendInt64(int64(v)) }
+func (arr *logfmtArrayEncoder) AppendInt16(v int16)     { arr.AppendInt64(int64(v)) }
+func (arr *logfmtArrayEncoder) AppendInt8(v int8)       { arr.AppendInt64(int64(v)) }
+func (arr *logfmtArrayEncoder) AppendUint64(v uint64)   { arr.AppendString(strconv.FormatUint(v, 10)) }
+func (arr *logfmtArrayEncoder) AppendUint(v uint)       { arr.AppendUint64(uint64(v)) }
+func (arr *logfmtArrayEncoder) AppendUint32(v uint32)   { arr.AppendUint64(uint64(v)) }
+func (arr *logfmtArrayEncoder) AppendUint16(v uint16)   { arr.AppendUint64(uint64(v)) }
+func (arr *logfmtArrayEncoder) AppendUint8(v uint8)     { arr.AppendUint64(uint64(v)) }
+func (arr *logfmtArrayEncoder) AppendUintptr(v uintptr) { arr.AppendUint64(uint64(v)) }
@@ -0,0 +1,43 @@
+package zapwriter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type testLogfmtObject struct{}
+
+func (testLogfmtObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", "x y")
+	enc.AddInt("n", 1)
+	return nil
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Encoding = "logfmt"
+	cfg.EncodingTime = "rfc3339"
+
+	logger, buf := testConfigLogger(t, cfg)
+	logger.Named("access").With(zap.String("host", "example.com")).Info("message \"text\"",
+		zap.Int("code", 200),
+		zap.String("empty", ""),
+		zap.String("eq", "a=b"),
+		zap.Strings("tags", []string{"a", "b"}),
+		zap.Object("obj", testLogfmtObject{}),
+		zap.Error(errors.New("line1\nline2")),
+		zap.Namespace("request"),
+		zap.Duration("time", 1500*time.Millisecond),
+		zap.Bool("ok", true),
+	)
+
+	out := buf.Capture()
+	expected := ` level=info logger=access msg="message \"text\"" host=example.com code=200 empty="" eq="a=b" tags=a,b obj.name="x y" obj.n=1 error="line1\nline2" request.time=1.5 request.ok=true` + "\n"
+	if len(out) < 3 || out[:3] != "ts=" || out[len(out)-len(expected):] != expected {
+		t.Fatal(out)
+	}
+}
@@ -0,0 +1,285 @@
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/lomik/zapwriter"
+	"github.com/lomik/zapwriter/httpbatch"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	zapwriter.RegisterScheme("loki", New)
+}
+
+// LokiOutput pushes entries to Loki push API. Entries are grouped into streams by labels:
+// logger name, level and static labels from DSN.
+//
+// DSN example:
+//
+//	loki://loki:3100/loki/api/v1/push?label.job=app&label.env=prod&format=protobuf&header.X-Scope-OrgID=team
+//
+// Params:
+//
+//	tls=true                  use https
+//	format=json               "json" or "protobuf" (snappy compressed)
+//	label.<name>=value        static label
+//	level-label=level         label of entry level, "-" to disable
+//	logger-label=logger       label of logger name, "-" to disable
+//
+// Batching and retry params are described in httpbatch.ParseOptions
+type LokiOutput struct {
+	*httpbatch.Batcher
+	labels      map[string]string
+	levelLabel  string
+	loggerLabel string
+}
+
+type entry struct {
+	labels map[string]string
+	key    string // labels in Loki format, stream key
+	time   time.Time
+	line   string
+}
+
+func New(path string) (zapwriter.Output, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	params := zapwriter.DSN(u.Query())
+
+	tls, err := params.Bool("tls", false)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/loki/api/v1/push"
+	}
+
+	opts, err := httpbatch.ParseOptions(u, scheme, "tls", "format", "level-label", "logger-label", "label.")
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := params.String("format", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var encode func(items []interface{}) ([]byte, error)
+	switch format {
+	case "json":
+		encode = encodeJSON
+		if opts.ContentType == "" {
+			opts.ContentType = "application/json"
+		}
+	case "protobuf":
+		encode = encodeProtobuf
+		if opts.ContentType == "" {
+			opts.ContentType = "application/x-protobuf"
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %#v", format)
+	}
+
+	labels := make(map[string]string)
+	for key := range params.Values {
+		if strings.HasPrefix(key, "label.") {
+			labels[strings.TrimPrefix(key, "label.")] = params.Get(key)
+		}
+	}
+
+	levelLabel, err := params.String("level-label", "level")
+	if err != nil {
+		return nil, err
+	}
+
+	loggerLabel, err := params.String("logger-label", "logger")
+	if err != nil {
+		return nil, err
+	}
+
+	return &LokiOutput{
+		Batcher:     httpbatch.NewBatcher(opts, encode, nil),
+		labels:      labels,
+		levelLabel:  levelLabel,
+		loggerLabel: loggerLabel,
+	}, nil
+}
+
+// labelsString returns labels in Loki format: {a="b", c="d"}
+func labelsString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func (l *LokiOutput) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) (int, error) {
+	labels := make(map[string]string, len(l.labels)+2)
+	for k, v := range l.labels {
+		labels[k] = v
+	}
+	if l.levelLabel != "-" {
+		labels[l.levelLabel] = ent.Level.String()
+	}
+	if l.loggerLabel != "-" && ent.LoggerName != "" {
+		labels[l.loggerLabel] = ent.LoggerName
+	}
+
+	t := ent.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	err := l.Add(&entry{
+		labels: labels,
+		key:    labelsString(labels),
+		time:   t,
+		line:   string(bytes.TrimRight(p, "\n")),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Write pushes message with static labels only
+func (l *LokiOutput) Write(p []byte) (int, error) {
+	labels := l.labels
+	err := l.Add(&entry{
+		labels: labels,
+		key:    labelsString(labels),
+		time:   time.Now(),
+		line:   string(bytes.TrimRight(p, "\n")),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// streams groups entries by labels keeping order of entries and order of first appearance of streams
+func streams(items []interface{}) [][]*entry {
+	var res [][]*entry
+	index := make(map[string]int)
+
+	for _, item := range items {
+		e := item.(*entry)
+		i, ok := index[e.key]
+		if !ok {
+			i = len(res)
+			index[e.key] = i
+			res = append(res, nil)
+		}
+		res[i] = append(res[i], e)
+	}
+
+	return res
+}
+
+type jsonStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func encodeJSON(items []interface{}) ([]byte, error) {
+	var req struct {
+		Streams []jsonStream `json:"streams"`
+	}
+
+	for _, entries := range streams(items) {
+		s := jsonStream{Stream: entries[0].labels, Values: make([][2]string, 0, len(entries))}
+		for _, e := range entries {
+			s.Values = append(s.Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+		}
+		req.Streams = append(req.Streams, s)
+	}
+
+	return json.Marshal(req)
+}
+
+// encodeProtobuf encodes logproto.PushRequest and compresses it with snappy:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func encodeProtobuf(items []interface{}) ([]byte, error) {
+	var req []byte
+
+	for _, entries := range streams(items) {
+		var stream []byte
+		stream = appendBytes(stream, 1, []byte(entries[0].key))
+
+		for _, e := range entries {
+			var ts []byte
+			if sec := e.time.Unix(); sec != 0 {
+				ts = appendVarint(ts, 1, uint64(sec))
+			}
+			if nanos := e.time.Nanosecond(); nanos != 0 {
+				ts = appendVarint(ts, 2, uint64(nanos))
+			}
+
+			var ent []byte
+			ent = appendBytes(ent, 1, ts)
+			ent = appendBytes(ent, 2, []byte(e.line))
+
+			stream = appendBytes(stream, 2, ent)
+		}
+
+		req = appendBytes(req, 1, stream)
+	}
+
+	return snappy.Encode(nil, req), nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// appendVarint appends varint field
+func appendVarint(b []byte, field int, v uint64) []byte {
+	b = appendUvarint(b, uint64(field)<<3)
+	return appendUvarint(b, v)
+}
+
+// appendBytes appends length-delimited field
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendUvarint(b, uint64(field)<<3|2)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
@@ -0,0 +1,98 @@
+package loki
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/lomik/zapwriter"
+)
+
+func TestLoki(t *testing.T) {
+	var mu sync.Mutex
+	var bodies [][]byte
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/push" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := zapwriter.NewConfig()
+	cfg.Encoding = "json"
+	cfg.TimeKey = "-"
+	cfg.File = strings.Replace(srv.URL, "http://", "loki://", 1) + "?label.job=app&flush-interval=1h"
+
+	if err := zapwriter.ApplyConfig([]zapwriter.Config{cfg}); err != nil {
+		t.Fatal(err)
+	}
+	defer zapwriter.ApplyConfig([]zapwriter.Config{zapwriter.NewConfig()})
+
+	zapwriter.Logger("access").Info("first")
+	zapwriter.Logger("access").Error("second")
+	zapwriter.Logger("access").Info("third")
+
+	if err := zapwriter.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(bodies) != 1 {
+		t.Fatalf("%d requests", len(bodies))
+	}
+
+	var req struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(bodies[0], &req); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(req.Streams) != 2 ||
+		req.Streams[0].Stream["job"] != "app" || req.Streams[0].Stream["level"] != "info" || req.Streams[0].Stream["logger"] != "access" ||
+		len(req.Streams[0].Values) != 2 || !strings.Contains(req.Streams[0].Values[1][1], `"message":"third"`) ||
+		req.Streams[1].Stream["level"] != "error" || len(req.Streams[1].Values) != 1 {
+		t.Fatal(string(bodies[0]))
+	}
+}
+
+func TestLokiProtobuf(t *testing.T) {
+	ts := time.Unix(1600000000, 5)
+	body, err := encodeProtobuf([]interface{}{
+		&entry{key: `{job="app"}`, time: ts, line: "message"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "\x0a\x22" + // streams
+		"\x0a\x0b" + `{job="app"}` + // labels
+		"\x12\x13" + // entries
+		"\x0a\x08\x08\x80\xa0\xf8\xfa\x05\x10\x05" + // timestamp
+		"\x12\x07message"
+	if string(req) != expected {
+		t.Fatalf("%q", req)
+	}
+}
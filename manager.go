@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -12,13 +13,14 @@ import (
 
 var _mutex sync.RWMutex
 var _manager Manager
+var _reloadMutex sync.Mutex
+
+// closeGracePeriod is a delay before closing writers of replaced manager.
+// Loggers obtained before ApplyConfig can still write during this period. Protected by _reloadMutex
+var closeGracePeriod = 5 * time.Second
 
 func init() {
-	replaceGlobalManager(&manager{
-		writers: make(map[string]WriteSyncer),
-		cores:   make(map[string][]zapcore.Core),
-		loggers: make(map[string]*zap.Logger),
-	})
+	replaceGlobalManager(newManager())
 }
 
 func replaceGlobalManager(m Manager) Manager {
@@ -35,14 +37,79 @@ func CheckConfig(conf []Config, allowNames []string) error {
 	return err
 }
 
+// ApplyConfig replaces global manager, writers of previous one are closed after grace period
 func ApplyConfig(conf []Config) error {
+	_reloadMutex.Lock()
+	defer _reloadMutex.Unlock()
+
+	return applyConfig(conf)
+}
+
+// applyConfig must be called under _reloadMutex
+func applyConfig(conf []Config) error {
 	m, err := NewManager(conf)
 	if err != nil {
 		return err
 	}
 
+	prev := replaceGlobalManager(m)
+	closeManager(prev, closeGracePeriod)
+
+	return nil
+}
+
+// closeManager syncs and closes manager after grace period
+func closeManager(m Manager, grace time.Duration) {
+	if m == nil {
+		return
+	}
+
+	if grace <= 0 {
+		m.Close()
+		return
+	}
+
+	go func() {
+		time.Sleep(grace)
+		m.Close()
+	}()
+}
+
+// ReloadConfig applies config to running manager. Writers with unchanged DSN are reused,
+// removed writers are closed after grace period. Loggers returned before reload write with new config
+func ReloadConfig(conf []Config) error {
+	_reloadMutex.Lock()
+	defer _reloadMutex.Unlock()
+
+	_mutex.RLock()
+	prev, ok := _manager.(*manager)
+	_mutex.RUnlock()
+
+	if !ok {
+		return applyConfig(conf)
+	}
+
+	if err := CheckConfig(conf, nil); err != nil {
+		return err
+	}
+
+	m, err := buildManager(conf, prev)
+	if err != nil {
+		return err
+	}
+
 	replaceGlobalManager(m)
 
+	// close removed writers only, others are used by new manager
+	removed := newManager()
+	for path, ws := range prev.writers {
+		if m.writers[path] != ws {
+			removed.writers[path] = ws
+		}
+	}
+	removed.outputs = prev.outputs
+	closeManager(removed, closeGracePeriod)
+
 	return nil
 }
 
@@ -60,15 +127,66 @@ func Logger(logger string) *zap.Logger {
 	return m.Logger(logger)
 }
 
+// Sync flushes writers of global manager
+func Sync() error {
+	_mutex.RLock()
+	m := _manager
+	_mutex.RUnlock()
+	return m.Sync()
+}
+
+// SetLevel changes level of all outputs of logger in global manager
+func SetLevel(logger string, level zapcore.Level) error {
+	_mutex.RLock()
+	m := _manager
+	_mutex.RUnlock()
+	return m.SetLevel(logger, level)
+}
+
+// Levels returns levels of global manager: logger name -> output -> level
+func Levels() map[string]map[string]zapcore.Level {
+	_mutex.RLock()
+	m := _manager
+	_mutex.RUnlock()
+	return m.Levels()
+}
+
 type Manager interface {
 	Default() *zap.Logger
 	Logger(logger string) *zap.Logger
+	Sync() error
+	Close() error
+	SetLevel(logger string, level zapcore.Level) error
+	Levels() map[string]map[string]zapcore.Level
 }
 
 type manager struct {
 	writers map[string]WriteSyncer    // path -> writer
 	cores   map[string][]zapcore.Core // logger name -> cores
 	loggers map[string]*zap.Logger    // logger name -> logger
+	swaps   map[string]*swapCore      // logger name -> core of logger, replaced on reload. Includes removed loggers
+	levels  map[string][]outputLevel  // logger name -> levels of outputs
+	outputs []zapcore.Core            // cores created by RegisterCoreScheme constructors
+
+	resolvedMu sync.RWMutex
+	resolved   map[string]*zap.Logger // requested name -> named logger of nearest configured parent
+}
+
+type outputLevel struct {
+	output string
+	level  zap.AtomicLevel
+}
+
+func newManager() *manager {
+	return &manager{
+		writers: make(map[string]WriteSyncer),
+		cores:   make(map[string][]zapcore.Core),
+		loggers: make(map[string]*zap.Logger),
+		swaps:   make(map[string]*swapCore),
+		levels:  make(map[string][]outputLevel),
+
+		resolved: make(map[string]*zap.Logger),
+	}
 }
 
 func NewManager(conf []Config) (Manager, error) {
@@ -82,11 +200,102 @@ func (m *manager) Default() *zap.Logger {
 	return zap.NewNop()
 }
 
+// maxResolvedLoggers limits number of cached named loggers of manager
+const maxResolvedLoggers = 1024
+
+// Logger returns logger configured for name. Dotted names inherit config of parent:
+// "http.access.slow" uses "http.access", then "http", then default logger
 func (m *manager) Logger(name string) *zap.Logger {
-	if logger, ok := m.loggers[name]; ok {
-		return logger.Named(name)
+	m.resolvedMu.RLock()
+	logger, ok := m.resolved[name]
+	m.resolvedMu.RUnlock()
+
+	if ok {
+		return logger
+	}
+
+	logger = m.resolve(name).Named(name)
+
+	// names built from request data must not grow cache without limit
+	m.resolvedMu.Lock()
+	if len(m.resolved) < maxResolvedLoggers {
+		m.resolved[name] = logger
+	}
+	m.resolvedMu.Unlock()
+
+	return logger
+}
+
+func (m *manager) resolve(name string) *zap.Logger {
+	for n := name; n != ""; {
+		if logger, ok := m.loggers[n]; ok {
+			return logger
+		}
+
+		i := strings.LastIndex(n, ".")
+		if i < 0 {
+			break
+		}
+		n = n[:i]
+	}
+	return m.Default()
+}
+
+func (m *manager) Sync() error {
+	var err error
+	for _, ws := range m.writers {
+		if e := ws.Sync(); e != nil && err == nil {
+			err = e
+		}
+	}
+	for _, core := range m.outputs {
+		if e := core.Sync(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Close syncs and closes all writers. Loggers of closed manager discard messages
+func (m *manager) Close() error {
+	err := m.Sync()
+	for _, ws := range m.writers {
+		if c, ok := ws.(closeable); ok {
+			if e := c.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	for _, core := range m.outputs {
+		if c, ok := core.(closeable); ok {
+			if e := c.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+func (m *manager) SetLevel(logger string, level zapcore.Level) error {
+	levels, ok := m.levels[logger]
+	if !ok {
+		return fmt.Errorf("unknown logger name %#v", logger)
+	}
+	for _, l := range levels {
+		l.level.SetLevel(level)
+	}
+	return nil
+}
+
+func (m *manager) Levels() map[string]map[string]zapcore.Level {
+	res := make(map[string]map[string]zapcore.Level)
+	for logger, levels := range m.levels {
+		res[logger] = make(map[string]zapcore.Level)
+		for _, l := range levels {
+			res[logger][l.output] = l.level.Level()
+		}
 	}
-	return m.Default().Named(name)
+	return res
 }
 
 func makeManager(conf []Config, checkOnly bool, allowNames []string) (Manager, error) {
@@ -111,6 +320,14 @@ func makeManager(conf []Config, checkOnly bool, allowNames []string) (Manager, e
 		if err != nil {
 			return nil, err
 		}
+
+		if _, err := cfg.fields(); err != nil {
+			return nil, err
+		}
+
+		if _, err := cfg.options(); err != nil {
+			return nil, err
+		}
 	}
 
 	// check complete
@@ -118,17 +335,56 @@ func makeManager(conf []Config, checkOnly bool, allowNames []string) (Manager, e
 		return nil, nil
 	}
 
-	m := &manager{
-		writers: make(map[string]WriteSyncer),
-		cores:   make(map[string][]zapcore.Core),
-		loggers: make(map[string]*zap.Logger),
+	m, err := buildManager(conf, nil)
+	if err != nil {
+		return nil, err
 	}
 
+	return m, nil
+}
+
+// buildManager creates writers, cores and loggers. Writers and loggers of prev are reused if not nil
+func buildManager(conf []Config, prev *manager) (*manager, error) {
+	m := newManager()
+
+	// writers created by this call, closed on error
+	created := make([]WriteSyncer, 0)
+	fail := func(err error) (*manager, error) {
+		for _, ws := range created {
+			if c, ok := ws.(closeable); ok {
+				c.Close()
+			}
+		}
+		for _, core := range m.outputs {
+			if c, ok := core.(closeable); ok {
+				c.Close()
+			}
+		}
+		return nil, err
+	}
+
+	// logger options are union of options of its outputs
+	options := make(map[string]*loggerOptions)
+	for _, cfg := range conf {
+		opts, err := cfg.options()
+		if err != nil {
+			return nil, err
+		}
+		if options[cfg.Logger] == nil {
+			options[cfg.Logger] = &opts
+		} else {
+			*options[cfg.Logger] = options[cfg.Logger].merge(opts)
+		}
+	}
+
+	// reused writers with changed DSN
+	replaced := make(map[*output]*output)
+
 	// create writers and cores
 	for _, cfg := range conf {
 		u, err := url.Parse(cfg.File)
 		if err != nil {
-			return nil, err
+			return fail(err)
 		}
 
 		if _, ok := m.cores[cfg.Logger]; !ok {
@@ -142,29 +398,93 @@ func makeManager(conf []Config, checkOnly bool, allowNames []string) (Manager, e
 
 		encoder, atomicLevel, err := cfg.encoder()
 		if err != nil {
-			return nil, err
+			return fail(err)
 		}
 
-		ws, ok := m.writers[u.Path]
+		// scheme creates core itself, cores are never shared and reused
+		if newCore, ok := coreScheme(u.Scheme); ok {
+			base, err := newCore(cfg.File, encoder, atomicLevel)
+			if err != nil {
+				return fail(err)
+			}
+			m.outputs = append(m.outputs, base)
+
+			core, err := cfg.wrapCore(base, options[cfg.Logger])
+			if err != nil {
+				return fail(err)
+			}
+
+			m.levels[cfg.Logger] = append(m.levels[cfg.Logger], outputLevel{output: cfg.File, level: atomicLevel})
+			m.cores[cfg.Logger] = append(m.cores[cfg.Logger], core)
+			continue
+		}
+
+		ws, ok := m.writers[writerKey(u)]
+		if !ok && prev != nil {
+			if ws, ok = prev.writers[writerKey(u)]; ok {
+				if o, isOutput := ws.(*output); isOutput && o.changed(cfg.File) {
+					// new DSN is opened aside, writer in use is replaced when whole config is built
+					n, err := New(cfg.File)
+					if err != nil {
+						return fail(err)
+					}
+					created = append(created, n)
+					replaced[o] = n.(*output)
+				}
+				m.writers[writerKey(u)] = ws
+			}
+		}
 		if !ok {
 			ws, err = New(cfg.File)
 			if err != nil {
-				return nil, err
+				return fail(err)
 			}
-			m.writers[u.Path] = ws
+			created = append(created, ws)
+			m.writers[writerKey(u)] = ws
 		}
 
-		core, err := cfg.core(encoder, ws, atomicLevel)
+		core, err := cfg.core(encoder, ws, atomicLevel, options[cfg.Logger])
 		if err != nil {
-			return nil, err
+			return fail(err)
 		}
 
+		m.levels[cfg.Logger] = append(m.levels[cfg.Logger], outputLevel{output: cfg.File, level: atomicLevel})
+
 		m.cores[cfg.Logger] = append(m.cores[cfg.Logger], core)
 	}
 
+	for o, n := range replaced {
+		o.replace(n)
+	}
+
 	// make loggers
 	for k, cores := range m.cores {
-		m.loggers[k] = zap.New(zapcore.NewTee(cores...))
+		tee := zapcore.NewTee(cores...)
+		if prev != nil && prev.swaps[k] != nil {
+			// loggers obtained before reload keep previous options
+			prev.swaps[k].swap(tee)
+			m.swaps[k] = prev.swaps[k]
+		} else {
+			m.swaps[k] = newSwapCore(tee)
+		}
+		m.loggers[k] = zap.New(m.swaps[k], options[k].zapOptions()...)
+	}
+
+	if prev == nil {
+		return m, nil
+	}
+
+	// loggers removed from config are routed to default and kept for next reloads
+	for k, sw := range prev.swaps {
+		if _, ok := m.swaps[k]; ok {
+			continue
+		}
+		if def, ok := m.swaps[""]; ok {
+			sw.swap(def)
+		} else {
+			sw.swap(zapcore.NewNopCore())
+		}
+		m.swaps[k] = sw
 	}
 
 	return m, nil
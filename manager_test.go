@@ -0,0 +1,311 @@
+package zapwriter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// setCloseGracePeriod changes grace period of replaced managers until the end of test
+func setCloseGracePeriod(t *testing.T, grace time.Duration) {
+	_reloadMutex.Lock()
+	prev := closeGracePeriod
+	closeGracePeriod = grace
+	_reloadMutex.Unlock()
+
+	t.Cleanup(func() {
+		_reloadMutex.Lock()
+		closeGracePeriod = prev
+		_reloadMutex.Unlock()
+	})
+}
+
+func TestReloadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	first := filepath.Join(dir, "first.log")
+	second := filepath.Join(dir, "second.log")
+
+	cfg := NewConfig()
+	cfg.File = first
+
+	setCloseGracePeriod(t, 0)
+
+	if err := ApplyConfig([]Config{cfg}); err != nil {
+		t.Fatal(err)
+	}
+	defer ApplyConfig([]Config{NewConfig()})
+
+	_mutex.RLock()
+	ws := _manager.(*manager).writers[first]
+	_mutex.RUnlock()
+
+	out := ws.(*output).out
+
+	logger := Default().With(zap.String("key", "value"))
+	logger.Debug("debug before reload")
+
+	cfg.Level = "debug"
+	if err := ReloadConfig([]Config{cfg}); err != nil {
+		t.Fatal(err)
+	}
+
+	_mutex.RLock()
+	if _manager.(*manager).writers[first] != ws {
+		t.Fatal("writer recreated")
+	}
+	_mutex.RUnlock()
+
+	if ws.(*output).out != out {
+		t.Fatal("file reopened")
+	}
+
+	logger.Debug("debug after reload")
+
+	c, _ := ioutil.ReadFile(first)
+	if strings.Contains(string(c), "debug before reload") || !strings.Contains(string(c), `debug after reload {"key": "value"}`) {
+		t.Fatal(string(c))
+	}
+
+	cfg.File = second
+	if err := ReloadConfig([]Config{cfg}); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("moved")
+
+	if n, _ := ws.Write([]byte("closed\n")); n != 0 {
+		t.Fatal("removed writer not closed")
+	}
+
+	c, _ = ioutil.ReadFile(second)
+	if !strings.Contains(string(c), `moved {"key": "value"}`) {
+		t.Fatal(string(c))
+	}
+}
+
+func TestReloadConfigFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	setCloseGracePeriod(t, 0)
+
+	cfg := NewConfig()
+	cfg.File = filepath.Join(dir, "a.log")
+
+	if err := ApplyConfig([]Config{cfg}); err != nil {
+		t.Fatal(err)
+	}
+	defer ApplyConfig([]Config{NewConfig()})
+
+	_mutex.RLock()
+	o := _manager.(*manager).writers[cfg.File].(*output)
+	_mutex.RUnlock()
+
+	out := o.out
+
+	resized := cfg
+	resized.File = cfg.File + "?max-size=10"
+	bogus := NewConfig()
+	bogus.Logger = "bogus"
+	bogus.File = "bogus://h/"
+
+	if err := ReloadConfig([]Config{resized, bogus}); err == nil {
+		t.Fatal("expected error of unknown scheme")
+	}
+
+	if o.out != out || o.dsn != cfg.File {
+		t.Fatal("writer changed by failed reload")
+	}
+
+	if err := ReloadConfig([]Config{resized}); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.out == out || o.dsn != resized.File {
+		t.Fatal("writer not reopened")
+	}
+
+	Default().Info("after reload")
+
+	c, _ := ioutil.ReadFile(cfg.File)
+	if !strings.Contains(string(c), "after reload") {
+		t.Fatal(string(c))
+	}
+}
+
+func TestReloadConfigRemovedLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	setCloseGracePeriod(t, 0)
+
+	def := NewConfig()
+	def.File = filepath.Join(dir, "first.log")
+
+	removed := NewConfig()
+	removed.Logger = "removed"
+	removed.File = filepath.Join(dir, "removed.log")
+
+	if err := ApplyConfig([]Config{def, removed}); err != nil {
+		t.Fatal(err)
+	}
+	defer ApplyConfig([]Config{NewConfig()})
+
+	logger := Logger("removed")
+
+	if err := ReloadConfig([]Config{def}); err != nil {
+		t.Fatal(err)
+	}
+
+	def.File = filepath.Join(dir, "second.log")
+	if err := ReloadConfig([]Config{def}); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("routed to default")
+
+	c, _ := ioutil.ReadFile(def.File)
+	if !strings.Contains(string(c), "routed to default") {
+		t.Fatal(string(c))
+	}
+
+	if err := ReloadConfig([]Config{def, removed}); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("added back")
+
+	c, _ = ioutil.ReadFile(removed.File)
+	if !strings.Contains(string(c), "added back") {
+		t.Fatal(string(c))
+	}
+}
+
+func TestApplyConfigClosesPrevious(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	setCloseGracePeriod(t, 10*time.Millisecond)
+
+	cfg := NewConfig()
+	cfg.File = filepath.Join(dir, "test.log")
+
+	if err := ApplyConfig([]Config{cfg}); err != nil {
+		t.Fatal(err)
+	}
+	defer ApplyConfig([]Config{NewConfig()})
+
+	logger := Default()
+
+	if err := ApplyConfig([]Config{cfg}); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("during grace period")
+	time.Sleep(100 * time.Millisecond)
+	logger.Info("after close")
+
+	c, _ := ioutil.ReadFile(cfg.File)
+	if !strings.Contains(string(c), "during grace period") || strings.Contains(string(c), "after close") {
+		t.Fatal(string(c))
+	}
+}
+
+func TestLoggerHierarchy(t *testing.T) {
+	buf := &testBuffer{}
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), buf, zapcore.DebugLevel)
+
+	m := newManager()
+	for _, name := range []string{"", "http", "http.access"} {
+		m.loggers[name] = zap.New(core).With(zap.String("config", name))
+	}
+
+	table := map[string]string{
+		"db":               `"logger":"db","msg":"","config":""`,
+		"http":             `"logger":"http","msg":"","config":"http"`,
+		"http.error":       `"logger":"http.error","msg":"","config":"http"`,
+		"http.access":      `"logger":"http.access","msg":"","config":"http.access"`,
+		"http.access.slow": `"logger":"http.access.slow","msg":"","config":"http.access"`,
+		"httpd":            `"logger":"httpd","msg":"","config":""`,
+	}
+
+	for name, expected := range table {
+		logger := m.Logger(name)
+		logger.Info("")
+		if out := buf.Capture(); !strings.Contains(out, expected) {
+			t.Fatalf("%#v: %s", name, out)
+		}
+		if m.Logger(name) != logger {
+			t.Fatalf("%#v: not cached", name)
+		}
+	}
+
+	for i := 0; i < 2*maxResolvedLoggers; i++ {
+		m.Logger(fmt.Sprintf("req.%d", i))
+	}
+
+	if len(m.resolved) != maxResolvedLoggers {
+		t.Fatalf("cache size %d", len(m.resolved))
+	}
+
+	m.Logger("req.overflow").Info("")
+	if out := buf.Capture(); !strings.Contains(out, `"logger":"req.overflow","msg":"","config":""`) {
+		t.Fatal(out)
+	}
+}
+
+func TestLoggerOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	setCloseGracePeriod(t, 0)
+
+	withCaller := NewConfig()
+	withCaller.File = filepath.Join(dir, "caller.log?stacktrace-level=error")
+	withCaller.Caller = true
+
+	plain := NewConfig()
+	plain.File = filepath.Join(dir, "plain.log")
+
+	if err := ApplyConfig([]Config{withCaller, plain}); err != nil {
+		t.Fatal(err)
+	}
+	defer ApplyConfig([]Config{NewConfig()})
+
+	Default().Error("message text")
+	Sync()
+
+	c, _ := ioutil.ReadFile(filepath.Join(dir, "caller.log"))
+	if !strings.Contains(string(c), "manager_test.go:") || !strings.Contains(string(c), `"stacktrace": "`) {
+		t.Fatal(string(c))
+	}
+
+	c, _ = ioutil.ReadFile(filepath.Join(dir, "plain.log"))
+	if strings.Contains(string(c), "manager_test.go:") || strings.Contains(string(c), "stacktrace") {
+		t.Fatal(string(c))
+	}
+}
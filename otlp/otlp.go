@@ -0,0 +1,149 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lomik/zapwriter"
+	"github.com/lomik/zapwriter/httpbatch"
+)
+
+func init() {
+	zapwriter.RegisterScheme("otlp+http", New)
+	zapwriter.RegisterScheme("otlp+https", New)
+}
+
+// OTLPOutput sends entries to OpenTelemetry collector with OTLP/HTTP JSON protocol.
+// Entries must be encoded with "otlp" encoding, records of one batch are merged into single
+// ExportLogsServiceRequest. Lines of other encodings are sent as body of record without resource.
+//
+// DSN example:
+//
+//	otlp+http://otel-collector:4318/v1/logs?batch-size=500&content-encoding=gzip
+//
+// Batching and retry params are described in httpbatch.ParseOptions
+type OTLPOutput struct {
+	*httpbatch.Batcher
+}
+
+// ExportLogsServiceRequest with raw resources, scopes and records
+type request struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  json.RawMessage `json:"resource,omitempty"`
+	ScopeLogs []scopeLogs     `json:"scopeLogs"`
+}
+
+type scopeLogs struct {
+	Scope      json.RawMessage   `json:"scope,omitempty"`
+	LogRecords []json.RawMessage `json:"logRecords"`
+}
+
+type partialSuccess struct {
+	PartialSuccess struct {
+		RejectedLogRecords json.Number `json:"rejectedLogRecords"`
+		ErrorMessage       string      `json:"errorMessage"`
+	} `json:"partialSuccess"`
+}
+
+func New(path string) (zapwriter.Output, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/logs"
+	}
+
+	opts, err := httpbatch.ParseOptions(u, strings.TrimPrefix(u.Scheme, "otlp+"))
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ContentType == "" {
+		opts.ContentType = "application/json"
+	}
+
+	return &OTLPOutput{httpbatch.NewBatcher(opts, encode, check)}, nil
+}
+
+// parse decodes line of "otlp" encoding, other lines are wrapped into record body
+func parse(p []byte) *request {
+	req := &request{}
+	if err := json.Unmarshal(p, req); err == nil && len(req.ResourceLogs) > 0 {
+		return req
+	}
+
+	body, _ := json.Marshal(string(bytes.TrimRight(p, "\n")))
+	record := fmt.Sprintf(`{"observedTimeUnixNano":"%d","body":{"stringValue":%s}}`, time.Now().UnixNano(), body)
+	return &request{ResourceLogs: []resourceLogs{{
+		ScopeLogs: []scopeLogs{{LogRecords: []json.RawMessage{json.RawMessage(record)}}},
+	}}}
+}
+
+func (o *OTLPOutput) Write(p []byte) (int, error) {
+	if err := o.Add(parse(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// encode merges requests grouping records by resource and scope in order of first appearance
+func encode(items []interface{}) ([]byte, error) {
+	var res request
+	resourceIndex := make(map[string]int)
+	scopeIndex := make(map[[2]string]int)
+
+	for _, item := range items {
+		for _, rl := range item.(*request).ResourceLogs {
+			rkey := string(rl.Resource)
+			ri, ok := resourceIndex[rkey]
+			if !ok {
+				ri = len(res.ResourceLogs)
+				resourceIndex[rkey] = ri
+				res.ResourceLogs = append(res.ResourceLogs, resourceLogs{Resource: rl.Resource})
+			}
+
+			for _, sl := range rl.ScopeLogs {
+				skey := [2]string{rkey, string(sl.Scope)}
+				si, ok := scopeIndex[skey]
+				if !ok {
+					si = len(res.ResourceLogs[ri].ScopeLogs)
+					scopeIndex[skey] = si
+					res.ResourceLogs[ri].ScopeLogs = append(res.ResourceLogs[ri].ScopeLogs, scopeLogs{Scope: sl.Scope})
+				}
+				scope := &res.ResourceLogs[ri].ScopeLogs[si]
+				scope.LogRecords = append(scope.LogRecords, sl.LogRecords...)
+			}
+		}
+	}
+
+	return json.Marshal(&res)
+}
+
+// check returns error of partial success response
+func check(resp *http.Response, body []byte, items []interface{}) error {
+	if len(body) == 0 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	var ps partialSuccess
+	if err := json.Unmarshal(body, &ps); err != nil {
+		return nil
+	}
+
+	rejected, _ := strconv.ParseInt(ps.PartialSuccess.RejectedLogRecords.String(), 10, 64)
+	if rejected > 0 || ps.PartialSuccess.ErrorMessage != "" {
+		return fmt.Errorf("%d log records rejected: %s", rejected, ps.PartialSuccess.ErrorMessage)
+	}
+	return nil
+}
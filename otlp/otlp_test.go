@@ -0,0 +1,81 @@
+package otlp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lomik/zapwriter"
+)
+
+func TestOTLP(t *testing.T) {
+	requests := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- body
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	cfg := zapwriter.NewConfig()
+	cfg.File = fmt.Sprintf("otlp+%s?flush-interval=1h", server.URL)
+	cfg.Encoding = "otlp"
+	cfg.Resource = map[string]string{"service.name": "app"}
+
+	logger, err := cfg.BuildLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Named("a").Info("first")
+	logger.Named("b").Info("second")
+	logger.Named("a").Info("third")
+
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	var req request
+	if err := json.Unmarshal(<-requests, &req); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(req.ResourceLogs) != 1 {
+		t.Fatalf("%#v", req)
+	}
+
+	scopes := req.ResourceLogs[0].ScopeLogs
+	if len(scopes) != 2 || string(scopes[0].Scope) != `{"name":"a"}` || len(scopes[0].LogRecords) != 2 ||
+		string(scopes[1].Scope) != `{"name":"b"}` || len(scopes[1].LogRecords) != 1 {
+		t.Fatalf("%#v", scopes)
+	}
+}
+
+func TestOTLPPartialSuccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"partialSuccess":{"rejectedLogRecords":"1","errorMessage":"too old"}}`))
+	}))
+	defer server.Close()
+
+	out, err := New(fmt.Sprintf("otlp+%s?flush-interval=1h", server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.(*OTLPOutput).Close()
+
+	// not otlp encoded line
+	out.Write([]byte("plain text\n"))
+
+	if err := out.Sync(); err == nil || err.Error() != "1 log records rejected: too old" {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,326 @@
+package zapwriter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// otlpEncoder writes every entry as OTLP/JSON ExportLogsServiceRequest with single LogRecord,
+// same format as used by otlpjsonfile receiver of OpenTelemetry collector. Fields are written to
+// attributes, resource attributes are static, logger name is instrumentation scope name.
+type otlpEncoder struct {
+	*jsonEncoder
+	resource []byte // encoded resource attributes
+}
+
+var otlpSeverity = map[zapcore.Level]int{
+	zapcore.DebugLevel:  5,
+	zapcore.InfoLevel:   9,
+	zapcore.WarnLevel:   13,
+	zapcore.ErrorLevel:  17,
+	zapcore.DPanicLevel: 18,
+	zapcore.PanicLevel:  19,
+	zapcore.FatalLevel:  21,
+}
+
+func NewOTLPEncoder(cfg zapcore.EncoderConfig, resource map[string]string) zapcore.Encoder {
+	keys := make([]string, 0, len(resource))
+	for k := range resource {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	enc := &otlpEncoder{jsonEncoder: newJSONEncoder(cfg, false)}
+	for _, k := range keys {
+		enc.AddString(k, resource[k])
+	}
+	enc.resource = append([]byte(nil), enc.buf.Bytes()...)
+	enc.buf.Reset()
+
+	return enc
+}
+
+// kv writes KeyValue with value written by fn
+func (enc *otlpEncoder) kv(key string, fn func()) {
+	enc.addElementSeparator()
+	enc.buf.AppendString(`{"key":`)
+	enc.jsonEncoder.AppendString(key)
+	enc.buf.AppendString(`,"value":`)
+	fn()
+	enc.buf.AppendByte('}')
+}
+
+// value writes AnyValue of given type with value written by fn
+func (enc *otlpEncoder) value(typ string, fn func()) {
+	enc.addElementSeparator()
+	enc.buf.AppendString(`{"`)
+	enc.buf.AppendString(typ)
+	enc.buf.AppendString(`":`)
+	fn()
+	enc.buf.AppendByte('}')
+}
+
+// list writes kvlistValue or arrayValue with values written by fn
+func (enc *otlpEncoder) list(typ string, fn func() error) (err error) {
+	enc.value(typ, func() {
+		enc.buf.AppendString(`{"values":[`)
+		err = fn()
+		enc.buf.AppendString(`]}`)
+	})
+	return
+}
+
+func (enc *otlpEncoder) closeOpenNamespaces() {
+	for i := 0; i < enc.openNamespaces; i++ {
+		enc.buf.AppendString(`]}}}`)
+	}
+}
+
+// appendGeneric writes value decoded from json
+func (enc *otlpEncoder) appendGeneric(v interface{}) {
+	switch v := v.(type) {
+	case string:
+		enc.AppendString(v)
+	case bool:
+		enc.AppendBool(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			enc.AppendInt64(i)
+		} else if f, err := v.Float64(); err == nil {
+			enc.AppendFloat64(f)
+		} else {
+			enc.AppendString(v.String())
+		}
+	case []interface{}:
+		enc.list("arrayValue", func() error {
+			for _, e := range v {
+				enc.appendGeneric(e)
+			}
+			return nil
+		})
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		enc.list("kvlistValue", func() error {
+			for _, k := range keys {
+				enc.kv(k, func() { enc.appendGeneric(v[k]) })
+			}
+			return nil
+		})
+	default:
+		// null
+		enc.addElementSeparator()
+		enc.buf.AppendString(`{}`)
+	}
+}
+
+func (enc *otlpEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
+	return enc.list("arrayValue", func() error { return arr.MarshalLogArray(enc) })
+}
+
+func (enc *otlpEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	return enc.list("kvlistValue", func() error {
+		old := enc.openNamespaces
+		enc.openNamespaces = 0
+		err := obj.MarshalLogObject(enc)
+		enc.closeOpenNamespaces()
+		enc.openNamespaces = old
+		return err
+	})
+}
+
+func (enc *otlpEncoder) AppendReflected(val interface{}) error {
+	marshaled, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	var generic interface{}
+	dec := json.NewDecoder(bytes.NewReader(marshaled))
+	dec.UseNumber()
+	if err := dec.Decode(&generic); err != nil {
+		return err
+	}
+	enc.appendGeneric(generic)
+	return nil
+}
+
+func (enc *otlpEncoder) AppendString(val string) {
+	enc.value("stringValue", func() { enc.jsonEncoder.AppendString(val) })
+}
+
+func (enc *otlpEncoder) AppendByteString(val []byte) {
+	enc.value("stringValue", func() { enc.jsonEncoder.AppendByteString(val) })
+}
+
+func (enc *otlpEncoder) AppendBool(val bool) {
+	enc.value("boolValue", func() { enc.buf.AppendBool(val) })
+}
+
+func (enc *otlpEncoder) AppendComplex128(val complex128) {
+	enc.value("stringValue", func() { enc.jsonEncoder.AppendComplex128(val) })
+}
+
+func (enc *otlpEncoder) AppendDuration(val time.Duration) {
+	enc.AppendString(val.String())
+}
+
+func (enc *otlpEncoder) AppendTime(val time.Time) {
+	enc.AppendString(val.Format(time.RFC3339Nano))
+}
+
+// AppendInt64 writes intValue, int64 is string in OTLP/JSON
+func (enc *otlpEncoder) AppendInt64(val int64) {
+	enc.value("intValue", func() {
+		enc.buf.AppendByte('"')
+		enc.buf.AppendInt(val)
+		enc.buf.AppendByte('"')
+	})
+}
+
+func (enc *otlpEncoder) AppendUint64(val uint64) {
+	if val > math.MaxInt64 {
+		enc.AppendString(strconv.FormatUint(val, 10))
+		return
+	}
+	enc.AppendInt64(int64(val))
+}
+
+func (enc *otlpEncoder) AppendFloat64(val float64) {
+	enc.value("doubleValue", func() { enc.appendFloat(val, 64) })
+}
+
+func (enc *otlpEncoder) AppendComplex64(v complex64) { enc.AppendComplex128(complex128(v)) }
+func (enc *otlpEncoder) AppendFloat32(v float32)     { enc.AppendFloat64(float64(v)) }
+func (enc *otlpEncoder) AppendInt(v int)             { enc.AppendInt64(int64(v)) }
+func (enc *otlpEncoder) AppendInt32(v int32)         { enc.AppendInt64(int64(v)) }
+func (enc *otlpEncoder) AppendInt16(v int16)         { enc.AppendInt64(int64(v)) }
+func (enc *otlpEncoder) AppendInt8(v int8)           { enc.AppendInt64(int64(v)) }
+func (enc *otlpEncoder) AppendUint(v uint)           { enc.AppendUint64(uint64(v)) }
+func (enc *otlpEncoder) AppendUint32(v uint32)       { enc.AppendUint64(uint64(v)) }
+func (enc *otlpEncoder) AppendUint16(v uint16)       { enc.AppendUint64(uint64(v)) }
+func (enc *otlpEncoder) AppendUint8(v uint8)         { enc.AppendUint64(uint64(v)) }
+func (enc *otlpEncoder) AppendUintptr(v uintptr)     { enc.AppendUint64(uint64(v)) }
+
+func (enc *otlpEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) (err error) {
+	enc.kv(key, func() { err = enc.AppendArray(arr) })
+	return
+}
+
+func (enc *otlpEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) (err error) {
+	enc.kv(key, func() { err = enc.AppendObject(obj) })
+	return
+}
+
+func (enc *otlpEncoder) AddReflected(key string, obj interface{}) (err error) {
+	enc.kv(key, func() { err = enc.AppendReflected(obj) })
+	return
+}
+
+// OpenNamespace opens kvlistValue, it is closed by closeOpenNamespaces
+func (enc *otlpEncoder) OpenNamespace(key string) {
+	enc.addElementSeparator()
+	enc.buf.AppendString(`{"key":`)
+	enc.jsonEncoder.AppendString(key)
+	enc.buf.AppendString(`,"value":{"kvlistValue":{"values":[`)
+	enc.openNamespaces++
+}
+
+func (enc *otlpEncoder) AddBinary(key string, val []byte) {
+	enc.kv(key, func() {
+		enc.value("bytesValue", func() { enc.jsonEncoder.AppendString(base64.StdEncoding.EncodeToString(val)) })
+	})
+}
+
+func (enc *otlpEncoder) AddByteString(k string, v []byte) {
+	enc.kv(k, func() { enc.AppendByteString(v) })
+}
+func (enc *otlpEncoder) AddBool(k string, v bool) { enc.kv(k, func() { enc.AppendBool(v) }) }
+func (enc *otlpEncoder) AddComplex128(k string, v complex128) {
+	enc.kv(k, func() { enc.AppendComplex128(v) })
+}
+func (enc *otlpEncoder) AddComplex64(k string, v complex64) { enc.AddComplex128(k, complex128(v)) }
+func (enc *otlpEncoder) AddDuration(k string, v time.Duration) {
+	enc.kv(k, func() { enc.AppendDuration(v) })
+}
+func (enc *otlpEncoder) AddFloat64(k string, v float64) { enc.kv(k, func() { enc.AppendFloat64(v) }) }
+func (enc *otlpEncoder) AddFloat32(k string, v float32) { enc.AddFloat64(k, float64(v)) }
+func (enc *otlpEncoder) AddInt64(k string, v int64)     { enc.kv(k, func() { enc.AppendInt64(v) }) }
+func (enc *otlpEncoder) AddInt(k string, v int)         { enc.AddInt64(k, int64(v)) }
+func (enc *otlpEncoder) AddInt32(k string, v int32)     { enc.AddInt64(k, int64(v)) }
+func (enc *otlpEncoder) AddInt16(k string, v int16)     { enc.AddInt64(k, int64(v)) }
+func (enc *otlpEncoder) AddInt8(k string, v int8)       { enc.AddInt64(k, int64(v)) }
+func (enc *otlpEncoder) AddString(k, v string)          { enc.kv(k, func() { enc.AppendString(v) }) }
+func (enc *otlpEncoder) AddTime(k string, v time.Time)  { enc.kv(k, func() { enc.AppendTime(v) }) }
+func (enc *otlpEncoder) AddUint64(k string, v uint64)   { enc.kv(k, func() { enc.AppendUint64(v) }) }
+func (enc *otlpEncoder) AddUint(k string, v uint)       { enc.AddUint64(k, uint64(v)) }
+func (enc *otlpEncoder) AddUint32(k string, v uint32)   { enc.AddUint64(k, uint64(v)) }
+func (enc *otlpEncoder) AddUint16(k string, v uint16)   { enc.AddUint64(k, uint64(v)) }
+func (enc *otlpEncoder) AddUint8(k string, v uint8)     { enc.AddUint64(k, uint64(v)) }
+func (enc *otlpEncoder) AddUintptr(k string, v uintptr) { enc.AddUint64(k, uint64(v)) }
+
+func (enc *otlpEncoder) Clone() zapcore.Encoder {
+	clone := enc.clone()
+	clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *otlpEncoder) clone() *otlpEncoder {
+	return &otlpEncoder{jsonEncoder: enc.jsonEncoder.clone(), resource: enc.resource}
+}
+
+func (enc *otlpEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+
+	final.buf.AppendString(`{"resourceLogs":[{"resource":{"attributes":[`)
+	final.buf.Write(enc.resource)
+	final.buf.AppendString(`]},"scopeLogs":[{"scope":{`)
+	if ent.LoggerName != "" {
+		final.buf.AppendString(`"name":`)
+		final.jsonEncoder.AppendString(ent.LoggerName)
+	}
+	final.buf.AppendString(`},"logRecords":[{"timeUnixNano":"`)
+	final.buf.AppendInt(ent.Time.UnixNano())
+	final.buf.AppendString(`","severityNumber":`)
+	final.buf.AppendInt(int64(otlpSeverity[ent.Level]))
+	final.buf.AppendString(`,"severityText":`)
+	final.jsonEncoder.AppendString(ent.Level.CapitalString())
+	final.buf.AppendString(`,"body":`)
+	final.AppendString(ent.Message)
+	final.buf.AppendString(`,"attributes":[`)
+
+	if ent.Caller.Defined {
+		final.AddString("code.filepath", ent.Caller.File)
+		final.AddInt("code.lineno", ent.Caller.Line)
+		if ent.Caller.Function != "" {
+			final.AddString("code.function", ent.Caller.Function)
+		}
+	}
+
+	if enc.buf.Len() > 0 {
+		final.addElementSeparator()
+		final.buf.Write(enc.buf.Bytes())
+	}
+	addFields(final, fields)
+	final.closeOpenNamespaces()
+	if ent.Stack != "" {
+		final.AddString("code.stacktrace", ent.Stack)
+	}
+
+	final.buf.AppendString(`]}]}]}]}`)
+	final.buf.AppendByte('\n')
+
+	ret := final.buf
+	putJSONEncoder(final.jsonEncoder)
+	return ret, nil
+}
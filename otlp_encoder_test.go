@@ -0,0 +1,66 @@
+package zapwriter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestOTLPEncoder(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Encoding = "otlp"
+	cfg.Resource = map[string]string{"service.name": "app"}
+
+	logger, buf := testConfigLogger(t, cfg)
+	logger.Named("access").With(zap.String("host", "example.com")).Warn("message text",
+		zap.Int("code", 200),
+		zap.Bool("ok", true),
+		zap.Strings("tags", []string{"a"}),
+		zap.Namespace("request"),
+		zap.Float64("time", 1.5),
+	)
+
+	var req map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+		t.Fatal(err, buf.String())
+	}
+
+	rl := req["resourceLogs"].([]interface{})[0].(map[string]interface{})
+	sl := rl["scopeLogs"].([]interface{})[0].(map[string]interface{})
+	record := sl["logRecords"].([]interface{})[0].(map[string]interface{})
+
+	var expected map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "app"}}]},
+		"scope": {"name": "access"},
+		"severityNumber": 13,
+		"severityText": "WARN",
+		"body": {"stringValue": "message text"},
+		"attributes": [
+			{"key": "host", "value": {"stringValue": "example.com"}},
+			{"key": "code", "value": {"intValue": "200"}},
+			{"key": "ok", "value": {"boolValue": true}},
+			{"key": "tags", "value": {"arrayValue": {"values": [{"stringValue": "a"}]}}},
+			{"key": "request", "value": {"kvlistValue": {"values": [{"key": "time", "value": {"doubleValue": 1.5}}]}}}
+		]
+	}`), &expected)
+
+	actual := map[string]interface{}{
+		"resource":       rl["resource"],
+		"scope":          sl["scope"],
+		"severityNumber": record["severityNumber"],
+		"severityText":   record["severityText"],
+		"body":           record["body"],
+		"attributes":     record["attributes"],
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatal(buf.String())
+	}
+
+	if _, ok := record["timeUnixNano"].(string); !ok {
+		t.Fatal(buf.String())
+	}
+}
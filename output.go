@@ -7,6 +7,9 @@ import (
 	"net/url"
 	"os"
 	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type WriteSyncer interface {
@@ -23,7 +26,17 @@ type Output interface {
 	Sync() error
 }
 
+// EntryWriter is implemented by outputs which need structured entry (level, logger name, caller, fields)
+// in addition to encoded message. Fields include fields added with logger.With
+type EntryWriter interface {
+	WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) (n int, err error)
+}
+
+// CoreConstructor creates core of output from DSN, encoder and level of Config entry
+type CoreConstructor func(dsn string, enc zapcore.Encoder, lvl zap.AtomicLevel) (zapcore.Core, error)
+
 var knownSchemes = make(map[string](func(string) (Output, error)))
+var knownCoreSchemes = make(map[string]CoreConstructor)
 var knownSchemesMutex sync.RWMutex
 
 func RegisterScheme(scheme string, constructor func(path string) (Output, error)) {
@@ -31,10 +44,64 @@ func RegisterScheme(scheme string, constructor func(path string) (Output, error)
 	if _, exists := knownSchemes[scheme]; exists {
 		log.Fatalf("scheme %#v already registered", scheme)
 	}
+	if _, exists := knownCoreSchemes[scheme]; exists {
+		log.Fatalf("scheme %#v already registered", scheme)
+	}
 	knownSchemes[scheme] = constructor
 	knownSchemesMutex.Unlock()
 }
 
+// RegisterCoreScheme registers scheme which creates zapcore.Core instead of Output.
+// Such schemes see level, logger name and fields of every entry.
+// If core has Close() error method it is called when manager is closed.
+// Cores are created for every Config entry and recreated on ReloadConfig, outputs with
+// expensive connections should use RegisterScheme and implement EntryWriter instead
+func RegisterCoreScheme(scheme string, constructor CoreConstructor) {
+	knownSchemesMutex.Lock()
+	if _, exists := knownSchemes[scheme]; exists {
+		log.Fatalf("scheme %#v already registered", scheme)
+	}
+	if _, exists := knownCoreSchemes[scheme]; exists {
+		log.Fatalf("scheme %#v already registered", scheme)
+	}
+	knownCoreSchemes[scheme] = constructor
+	knownSchemesMutex.Unlock()
+}
+
+func coreScheme(scheme string) (CoreConstructor, bool) {
+	knownSchemesMutex.RLock()
+	constructor, exists := knownCoreSchemes[scheme]
+	knownSchemesMutex.RUnlock()
+	return constructor, exists
+}
+
+// NewOutputCore returns core which writes entries encoded by enc to out.
+// If out is EntryWriter it receives entries and fields too.
+// Close of core closes out. Helper for RegisterCoreScheme constructors
+func NewOutputCore(enc zapcore.Encoder, lvl zapcore.LevelEnabler, out Output) zapcore.Core {
+	ew, ok := out.(entryOutput)
+	if !ok {
+		ew = writerEntryOutput{out}
+	}
+	return &entryCore{LevelEnabler: lvl, enc: enc, out: ew}
+}
+
+// writerEntryOutput is entryOutput of plain Output
+type writerEntryOutput struct {
+	Output
+}
+
+func (w writerEntryOutput) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) (int, error) {
+	return w.Write(p)
+}
+
+func (w writerEntryOutput) Close() error {
+	if c, ok := w.Output.(closeable); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 type output struct {
 	sync.RWMutex
 	out       WriteSyncer
@@ -54,6 +121,9 @@ func New(dsn string) (Output, error) {
 }
 
 func (o *output) apply(dsn string) error {
+	o.Lock()
+	defer o.Unlock()
+
 	if dsn == o.dsn && o.out != nil { // nothing changed
 		return nil
 	}
@@ -66,34 +136,32 @@ func (o *output) apply(dsn string) error {
 		return err
 	}
 
-	if u.Path == "" || u.Path == "stderr" {
+	if u.Scheme != "" && u.Scheme != "file" {
+		knownSchemesMutex.RLock()
+		newFunc, exists := knownSchemes[u.Scheme]
+		knownSchemesMutex.RUnlock()
+
+		if !exists {
+			return fmt.Errorf("unknown scheme %#v", u.Scheme)
+		}
+
+		newOut, err = newFunc(u.String())
+		if err != nil {
+			return err
+		}
+		if _, ok := newOut.(closeable); ok {
+			newCloseable = true
+		}
+	} else if u.Path == "" || u.Path == "stderr" {
 		newOut = os.Stderr
 	} else if u.Path == "stdout" {
 		newOut = os.Stdout
 	} else {
-		if u.Scheme == "" || u.Scheme == "file" {
-			newOut, err = File(u.Path)
-			if err != nil {
-				return err
-			}
-			newCloseable = true
-		} else {
-			knownSchemesMutex.RLock()
-			newFunc, exists := knownSchemes[u.Scheme]
-			knownSchemesMutex.RUnlock()
-
-			if !exists {
-				return fmt.Errorf("unknown scheme %#v", u.Scheme)
-			}
-
-			newOut, err = newFunc(u.String())
-			if err != nil {
-				return err
-			}
-			if _, ok := newOut.(closeable); ok {
-				newCloseable = true
-			}
+		newOut, err = File(dsn)
+		if err != nil {
+			return err
 		}
+		newCloseable = true
 	}
 
 	if o.out != nil && o.closeable {
@@ -105,10 +173,37 @@ func (o *output) apply(dsn string) error {
 
 	o.out = newOut
 	o.closeable = newCloseable
+	o.dsn = dsn
 
 	return nil
 }
 
+// changed returns true if output is opened with other DSN
+func (o *output) changed(dsn string) bool {
+	o.RLock()
+	defer o.RUnlock()
+	return o.dsn != dsn
+}
+
+// replace closes underlying writer of o and moves writer of n to o. n must not be used after call
+func (o *output) replace(n *output) {
+	n.Lock()
+	newOut, newCloseable, dsn := n.out, n.closeable, n.dsn
+	n.out = nil
+	n.Unlock()
+
+	o.Lock()
+	if o.out != nil && o.closeable {
+		if c, ok := o.out.(closeable); ok {
+			c.Close()
+		}
+	}
+	o.out = newOut
+	o.closeable = newCloseable
+	o.dsn = dsn
+	o.Unlock()
+}
+
 func (o *output) Sync() (err error) {
 	o.RLock()
 	if o.out != nil {
@@ -118,6 +213,25 @@ func (o *output) Sync() (err error) {
 	return
 }
 
+// wantsEntries returns true if underlying output is EntryWriter
+func (o *output) wantsEntries() bool {
+	o.RLock()
+	_, ok := o.out.(EntryWriter)
+	o.RUnlock()
+	return ok
+}
+
+func (o *output) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) (n int, err error) {
+	o.RLock()
+	if ew, ok := o.out.(EntryWriter); ok {
+		n, err = ew.WriteEntry(ent, fields, p)
+	} else if o.out != nil {
+		n, err = o.out.Write(p)
+	}
+	o.RUnlock()
+	return
+}
+
 func (o *output) Write(p []byte) (n int, err error) {
 	o.RLock()
 	if o.out != nil {
@@ -126,3 +240,23 @@ func (o *output) Write(p []byte) (n int, err error) {
 	o.RUnlock()
 	return
 }
+
+func (o *output) Close() (err error) {
+	o.Lock()
+	if o.out != nil && o.closeable {
+		if c, ok := o.out.(closeable); ok {
+			err = c.Close()
+		}
+	}
+	o.out = nil
+	o.Unlock()
+	return
+}
+
+// writerKey returns key of writer in manager. Outputs with same key share one writer
+func writerKey(u *url.URL) string {
+	if u.Scheme == "" || u.Scheme == "file" {
+		return u.Path
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
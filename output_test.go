@@ -5,6 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestOutputFile(t *testing.T) {
@@ -29,3 +32,103 @@ func TestOutputFile(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+type testSchemeCore struct {
+	zapcore.LevelEnabler
+	entries *[]zapcore.Entry
+	closed  *bool
+}
+
+func (c testSchemeCore) With([]zapcore.Field) zapcore.Core { return c }
+func (c testSchemeCore) Sync() error                       { return nil }
+func (c testSchemeCore) Close() error                      { *c.closed = true; return nil }
+
+func (c testSchemeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c testSchemeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	*c.entries = append(*c.entries, ent)
+	return nil
+}
+
+func TestRegisterCoreScheme(t *testing.T) {
+	var entries []zapcore.Entry
+	var closed bool
+
+	RegisterCoreScheme("test-core", func(dsn string, enc zapcore.Encoder, lvl zap.AtomicLevel) (zapcore.Core, error) {
+		return testSchemeCore{LevelEnabler: lvl, entries: &entries, closed: &closed}, nil
+	})
+
+	setCloseGracePeriod(t, 0)
+
+	cfg := NewConfig()
+	cfg.File = "test-core://host/?level=warn"
+	if err := ApplyConfig([]Config{cfg}); err != nil {
+		t.Fatal(err)
+	}
+
+	Logger("access").Info("skipped")
+	Logger("access").Error("message text")
+
+	if len(entries) != 1 || entries[0].Message != "message text" || entries[0].LoggerName != "access" {
+		t.Fatalf("%#v", entries)
+	}
+
+	ApplyConfig([]Config{NewConfig()})
+	if !closed {
+		t.Fatal("core not closed")
+	}
+}
+
+type testEntryOutput struct {
+	levels *[]zapcore.Level
+}
+
+func (o testEntryOutput) Write(p []byte) (int, error) { return len(p), nil }
+func (o testEntryOutput) Sync() error                 { return nil }
+
+func (o testEntryOutput) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) (int, error) {
+	*o.levels = append(*o.levels, ent.Level)
+	return len(p), nil
+}
+
+func TestEntryWriterScheme(t *testing.T) {
+	var levels []zapcore.Level
+	var opened int
+
+	RegisterScheme("test-entry", func(dsn string) (Output, error) {
+		opened++
+		return testEntryOutput{levels: &levels}, nil
+	})
+
+	setCloseGracePeriod(t, 0)
+
+	access := NewConfig()
+	access.Logger = "access"
+	access.File = "test-entry://host/"
+
+	conf := []Config{NewConfig(), access}
+	conf[0].File = access.File
+
+	if err := ApplyConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer ApplyConfig([]Config{NewConfig()})
+
+	if err := ReloadConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	Logger("access").Warn("message text")
+
+	if opened != 1 {
+		t.Fatalf("output opened %d times", opened)
+	}
+	if len(levels) != 1 || levels[0] != zapcore.WarnLevel {
+		t.Fatalf("%#v", levels)
+	}
+}
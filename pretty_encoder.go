@@ -0,0 +1,138 @@
+package zapwriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiDim   = "\x1b[2m"
+	ansiCyan  = "\x1b[36m"
+)
+
+var prettyLevelColors = map[zapcore.Level]string{
+	zapcore.DebugLevel:  "\x1b[35m",
+	zapcore.InfoLevel:   "\x1b[34m",
+	zapcore.WarnLevel:   "\x1b[33m",
+	zapcore.ErrorLevel:  "\x1b[31m",
+	zapcore.DPanicLevel: "\x1b[1;31m",
+	zapcore.PanicLevel:  "\x1b[1;31m",
+	zapcore.FatalLevel:  "\x1b[1;31m",
+}
+
+// width of logger name column
+const prettyNameWidth = 16
+
+// prettyEncoder is mixedEncoder for terminals: colored levels, dimmed timestamps,
+// aligned logger names, fields and stacktrace on separate indented lines
+type prettyEncoder struct {
+	mixedEncoder
+	color bool
+}
+
+func NewPrettyEncoder(cfg zapcore.EncoderConfig, color bool) zapcore.Encoder {
+	return prettyEncoder{mixedEncoder: mixedEncoder{newJSONEncoder(cfg, true)}, color: color}
+}
+
+// isTerminal returns true if f is character device
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (enc prettyEncoder) Clone() zapcore.Encoder {
+	return prettyEncoder{mixedEncoder: enc.mixedEncoder.Clone().(mixedEncoder), color: enc.color}
+}
+
+func (enc prettyEncoder) style(buf *buffer.Buffer, style string, fn func()) {
+	if enc.color && style != "" {
+		buf.AppendString(style)
+		fn()
+		buf.AppendString(ansiReset)
+		return
+	}
+	fn()
+}
+
+func (enc prettyEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line := bufferpool.Get()
+
+	wr := getDirectWriteEncoder()
+	wr.buf = line
+
+	if enc.TimeKey != "" && enc.EncodeTime != nil {
+		enc.style(line, ansiDim, func() { enc.EncodeTime(ent.Time, wr) })
+		line.AppendByte(' ')
+	}
+
+	if enc.LevelKey != "" {
+		level := ent.Level.CapitalString()
+		enc.style(line, prettyLevelColors[ent.Level], func() { line.AppendString(level) })
+		// custom levels like LEVEL(-2) are longer
+		if len(level) < 7 {
+			line.AppendString(strings.Repeat(" ", 7-len(level)))
+		}
+	}
+
+	if enc.NameKey != "" {
+		name := ent.LoggerName
+		if name != "" {
+			enc.style(line, ansiCyan, func() { line.AppendString(name) })
+		}
+		if len(name) < prettyNameWidth {
+			line.AppendString(strings.Repeat(" ", prettyNameWidth-len(name)))
+		}
+		line.AppendByte(' ')
+	}
+
+	if ent.Caller.Defined && enc.CallerKey != "" && enc.EncodeCaller != nil {
+		enc.style(line, ansiDim, func() { enc.EncodeCaller(ent.Caller, wr) })
+		line.AppendByte(' ')
+	}
+
+	putDirectWriteEncoder(wr)
+
+	if enc.MessageKey != "" {
+		line.AppendString(ent.Message)
+	}
+
+	// fields as indented json object
+	final := enc.clone()
+	final.buf.AppendByte('{')
+	if enc.buf.Len() > 0 {
+		final.buf.Write(enc.buf.Bytes())
+	}
+	addFields(final, fields)
+	final.closeOpenNamespaces()
+	final.buf.AppendByte('}')
+
+	if final.buf.Len() > 2 {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, final.buf.Bytes(), "    ", "  "); err != nil {
+			indented.Reset()
+			indented.Write(final.buf.Bytes())
+		}
+		line.AppendString("\n    ")
+		line.Write(indented.Bytes())
+	}
+	final.buf.Free()
+	putJSONEncoder(final)
+
+	if ent.Stack != "" && enc.StacktraceKey != "" {
+		stack := "    " + strings.Replace(ent.Stack, "\n", "\n    ", -1)
+		line.AppendByte('\n')
+		enc.style(line, ansiDim, func() { line.AppendString(stack) })
+	}
+
+	line.AppendByte('\n')
+	return line, nil
+}
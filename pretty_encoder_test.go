@@ -0,0 +1,65 @@
+package zapwriter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestPrettyEncoder(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Encoding = "pretty"
+	cfg.EncodingTime = "rfc3339"
+
+	// buffer is not a terminal
+	logger, buf := testConfigLogger(t, cfg)
+	logger.Named("access").With(zap.String("host", "example.com")).Warn("message text",
+		zap.Int("code", 200),
+		zap.Namespace("request"),
+		zap.String("method", "GET"),
+	)
+
+	lines := strings.Split(buf.Capture(), "\n")
+	expected := []string{
+		`WARN   access           message text`,
+		`    {`,
+		`      "host": "example.com",`,
+		`      "code": 200,`,
+		`      "request": {`,
+		`        "method": "GET"`,
+		`      }`,
+		`    }`,
+		``,
+	}
+	if len(lines) != len(expected) || !strings.HasSuffix(lines[0], " "+expected[0]) || strings.Contains(lines[0], "\x1b") {
+		t.Fatalf("%q", lines)
+	}
+	for i := 1; i < len(expected); i++ {
+		if lines[i] != expected[i] {
+			t.Fatalf("%q", lines)
+		}
+	}
+
+	cfg.File = "stderr?color=always"
+	logger, buf = testConfigLogger(t, cfg)
+	logger.Error("message text", zap.Error(errors.New("fail")))
+
+	if out := buf.Capture(); !strings.Contains(out, "\x1b[31mERROR\x1b[0m") || !strings.HasPrefix(out, "\x1b[2m20") {
+		t.Fatalf("%q", out)
+	}
+
+	// level name longer than padding
+	enc := NewPrettyEncoder(zap.NewDevelopmentEncoderConfig(), false)
+	out, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.Level(-2), Message: "custom level"}, nil)
+	if err != nil || !strings.Contains(out.String(), "LEVEL(-2) ") || !strings.Contains(out.String(), "custom level") {
+		t.Fatalf("%q, %v", out.String(), err)
+	}
+
+	cfg.File = "stderr?color=unknown"
+	if err := cfg.Check(); err == nil {
+		t.Fatal("error expected")
+	}
+}
@@ -0,0 +1,322 @@
+package zapwriter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactor masks values of fields with matched keys and parts of string values matched by patterns
+type redactor struct {
+	keys     []string // lowercase glob patterns of dotted key path
+	patterns []*regexp.Regexp
+	replace  string // replacement, "hash" - sha256 prefix of value
+}
+
+func newRedactor(cfg RedactConfig) (*redactor, error) {
+	r := &redactor{replace: cfg.Replace}
+	if r.replace == "" {
+		r.replace = "***"
+	}
+
+	for _, key := range cfg.Keys {
+		key = strings.ToLower(key)
+		if _, err := path.Match(key, ""); err != nil {
+			return nil, fmt.Errorf("redact key %#v: %s", key, err.Error())
+		}
+		r.keys = append(r.keys, key)
+	}
+
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %#v: %s", pattern, err.Error())
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+// matchKey checks dotted key path like "request.user.password". "*.password" matches top-level "password" too
+func (r *redactor) matchKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+		if strings.HasPrefix(pattern, "*.") {
+			if ok, _ := path.Match(pattern[2:], key); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *redactor) mask(s string) string {
+	if r.replace == "hash" {
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	return r.replace
+}
+
+func (r *redactor) redactString(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllStringFunc(s, r.mask)
+	}
+	return s
+}
+
+// maskField replaces field with masked string value
+func (r *redactor) maskField(f zapcore.Field) zapcore.Field {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return zap.String(f.Key, r.mask(fmt.Sprint(enc.Fields[f.Key])))
+}
+
+// fields returns redacted copy of fields. prefix is dotted path of namespace opened by previous fields,
+// returned prefix includes namespaces opened by fields
+func (r *redactor) fields(fields []zapcore.Field, prefix string) ([]zapcore.Field, string) {
+	res := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		res[i] = f
+
+		switch f.Type {
+		case zapcore.SkipType:
+			continue
+		case zapcore.NamespaceType:
+			prefix += f.Key + "."
+			continue
+		case zapcore.InlineMarshalerType:
+			res[i].Interface = redactObject{f.Interface.(zapcore.ObjectMarshaler), r, prefix}
+			continue
+		}
+
+		key := prefix + f.Key
+		if r.matchKey(key) {
+			res[i] = r.maskField(f)
+			continue
+		}
+
+		switch f.Type {
+		case zapcore.StringType:
+			res[i].String = r.redactString(f.String)
+		case zapcore.ByteStringType:
+			res[i] = zap.String(f.Key, r.redactString(string(f.Interface.([]byte))))
+		case zapcore.ObjectMarshalerType:
+			res[i].Interface = redactObject{f.Interface.(zapcore.ObjectMarshaler), r, key + "."}
+		case zapcore.ArrayMarshalerType:
+			res[i].Interface = redactArray{f.Interface.(zapcore.ArrayMarshaler), r, key + "."}
+		case zapcore.ErrorType, zapcore.StringerType:
+			if f.Interface == nil {
+				continue
+			}
+			var s string
+			if err, ok := f.Interface.(error); ok && f.Type == zapcore.ErrorType {
+				s = err.Error()
+			} else if stringer, ok := f.Interface.(fmt.Stringer); ok {
+				s = stringer.String()
+			}
+			if redacted := r.redactString(s); redacted != s {
+				res[i] = zap.String(f.Key, redacted)
+			}
+		}
+	}
+	return res, prefix
+}
+
+// redactCore redacts fields and message before encoding
+type redactCore struct {
+	zapcore.Core
+	redactor *redactor
+	prefix   string // namespace opened by With
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	redacted, prefix := c.redactor.fields(fields, c.prefix)
+	return &redactCore{
+		Core:     c.Core.With(redacted),
+		redactor: c.redactor,
+		prefix:   prefix,
+	}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.redactor.redactString(ent.Message)
+	redacted, _ := c.redactor.fields(fields, c.prefix)
+	return c.Core.Write(ent, redacted)
+}
+
+type redactObject struct {
+	zapcore.ObjectMarshaler
+	redactor *redactor
+	prefix   string
+}
+
+func (o redactObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.ObjectMarshaler.MarshalLogObject(&redactObjectEncoder{enc, o.redactor, o.prefix})
+}
+
+type redactArray struct {
+	zapcore.ArrayMarshaler
+	redactor *redactor
+	prefix   string
+}
+
+func (a redactArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return a.ArrayMarshaler.MarshalLogArray(&redactArrayEncoder{enc, a.redactor, a.prefix})
+}
+
+// redactObjectEncoder redacts values of nested objects
+type redactObjectEncoder struct {
+	zapcore.ObjectEncoder
+	redactor *redactor
+	prefix   string
+}
+
+// masked adds masked value if key matches
+func (e *redactObjectEncoder) masked(key string, val interface{}) bool {
+	if !e.redactor.matchKey(e.prefix + key) {
+		return false
+	}
+	e.ObjectEncoder.AddString(key, e.redactor.mask(fmt.Sprint(val)))
+	return true
+}
+
+func (e *redactObjectEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	if e.masked(key, "array") {
+		return nil
+	}
+	return e.ObjectEncoder.AddArray(key, redactArray{arr, e.redactor, e.prefix + key + "."})
+}
+
+func (e *redactObjectEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	if e.masked(key, "object") {
+		return nil
+	}
+	return e.ObjectEncoder.AddObject(key, redactObject{obj, e.redactor, e.prefix + key + "."})
+}
+
+func (e *redactObjectEncoder) AddReflected(key string, val interface{}) error {
+	if e.masked(key, val) {
+		return nil
+	}
+	return e.ObjectEncoder.AddReflected(key, val)
+}
+
+func (e *redactObjectEncoder) OpenNamespace(key string) {
+	e.ObjectEncoder.OpenNamespace(key)
+	e.prefix += key + "."
+}
+
+func (e *redactObjectEncoder) AddString(key, val string) {
+	if !e.masked(key, val) {
+		e.ObjectEncoder.AddString(key, e.redactor.redactString(val))
+	}
+}
+
+func (e *redactObjectEncoder) AddByteString(key string, val []byte) {
+	if !e.masked(key, string(val)) {
+		e.ObjectEncoder.AddString(key, e.redactor.redactString(string(val)))
+	}
+}
+
+func (e *redactObjectEncoder) AddBinary(key string, val []byte) {
+	if !e.masked(key, val) {
+		e.ObjectEncoder.AddBinary(key, val)
+	}
+}
+
+func (e *redactObjectEncoder) AddBool(key string, val bool) {
+	if !e.masked(key, val) {
+		e.ObjectEncoder.AddBool(key, val)
+	}
+}
+
+func (e *redactObjectEncoder) AddComplex128(key string, val complex128) {
+	if !e.masked(key, val) {
+		e.ObjectEncoder.AddComplex128(key, val)
+	}
+}
+
+func (e *redactObjectEncoder) AddDuration(key string, val time.Duration) {
+	if !e.masked(key, val) {
+		e.ObjectEncoder.AddDuration(key, val)
+	}
+}
+
+func (e *redactObjectEncoder) AddFloat64(key string, val float64) {
+	if !e.masked(key, val) {
+		e.ObjectEncoder.AddFloat64(key, val)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt64(key string, val int64) {
+	if !e.masked(key, val) {
+		e.ObjectEncoder.AddInt64(key, val)
+	}
+}
+
+func (e *redactObjectEncoder) AddTime(key string, val time.Time) {
+	if !e.masked(key, val) {
+		e.ObjectEncoder.AddTime(key, val)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint64(key string, val uint64) {
+	if !e.masked(key, val) {
+		e.ObjectEncoder.AddUint64(key, val)
+	}
+}
+
+func (e *redactObjectEncoder) AddComplex64(k string, v complex64) { e.AddComplex128(k, complex128(v)) }
+func (e *redactObjectEncoder) AddFloat32(k string, v float32)     { e.AddFloat64(k, float64(v)) }
+func (e *redactObjectEncoder) AddInt(k string, v int)             { e.AddInt64(k, int64(v)) }
+func (e *redactObjectEncoder) AddInt32(k string, v int32)         { e.AddInt64(k, int64(v)) }
+func (e *redactObjectEncoder) AddInt16(k string, v int16)         { e.AddInt64(k, int64(v)) }
+func (e *redactObjectEncoder) AddInt8(k string, v int8)           { e.AddInt64(k, int64(v)) }
+func (e *redactObjectEncoder) AddUint(k string, v uint)           { e.AddUint64(k, uint64(v)) }
+func (e *redactObjectEncoder) AddUint32(k string, v uint32)       { e.AddUint64(k, uint64(v)) }
+func (e *redactObjectEncoder) AddUint16(k string, v uint16)       { e.AddUint64(k, uint64(v)) }
+func (e *redactObjectEncoder) AddUint8(k string, v uint8)         { e.AddUint64(k, uint64(v)) }
+func (e *redactObjectEncoder) AddUintptr(k string, v uintptr)     { e.AddUint64(k, uint64(v)) }
+
+// redactArrayEncoder redacts string elements and nested objects of arrays.
+// Key path of array elements is key path of array
+type redactArrayEncoder struct {
+	zapcore.ArrayEncoder
+	redactor *redactor
+	prefix   string
+}
+
+func (e *redactArrayEncoder) AppendString(val string) {
+	e.ArrayEncoder.AppendString(e.redactor.redactString(val))
+}
+
+func (e *redactArrayEncoder) AppendByteString(val []byte) {
+	e.ArrayEncoder.AppendString(e.redactor.redactString(string(val)))
+}
+
+func (e *redactArrayEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(redactArray{arr, e.redactor, e.prefix})
+}
+
+func (e *redactArrayEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(redactObject{obj, e.redactor, e.prefix})
+}
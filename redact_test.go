@@ -0,0 +1,118 @@
+package zapwriter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type testCredentials struct {
+	user     string
+	password string
+	headers  []string
+}
+
+func (c testCredentials) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user", c.user)
+	enc.AddString("password", c.password)
+	return enc.AddArray("headers", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, h := range c.headers {
+			arr.AppendString(h)
+		}
+		return nil
+	}))
+}
+
+func testRedactConfig(encoding string) Config {
+	cfg := NewConfig()
+	cfg.Encoding = encoding
+	cfg.TimeKey = "-"
+	cfg.Redact = RedactConfig{
+		Keys:     []string{"token", "*.password"},
+		Patterns: []string{`Bearer [A-Za-z0-9._-]+`, `\b\d{4}([ -]?\d{4}){3}\b`},
+	}
+	return cfg
+}
+
+func TestRedactKeys(t *testing.T) {
+	for _, encoding := range []string{"json", "mixed", "console"} {
+		logger, buf := testConfigLogger(t, testRedactConfig(encoding))
+
+		logger.With(zap.String("Token", "secret1")).Info("login",
+			zap.Int("password", 12345),
+			zap.Object("auth", testCredentials{user: "bob", password: "secret2", headers: []string{"Authorization: Bearer abc.def"}}),
+			zap.Namespace("request"),
+			zap.String("password", "secret3"),
+			zap.String("path", "/login"),
+		)
+
+		out := buf.Capture()
+		for _, secret := range []string{"secret1", "12345", "secret2", "secret3", "abc.def"} {
+			if strings.Contains(out, secret) {
+				t.Fatalf("%s: %s", encoding, out)
+			}
+		}
+		for _, s := range []string{"bob", "/login", "Authorization: ***"} {
+			if !strings.Contains(out, s) {
+				t.Fatalf("%s: %s", encoding, out)
+			}
+		}
+	}
+}
+
+func TestRedactNamespace(t *testing.T) {
+	cfg := testRedactConfig("json")
+	cfg.Redact.Keys = []string{"request.user.password"}
+
+	logger, buf := testConfigLogger(t, cfg)
+	logger = logger.With(zap.Namespace("request"))
+
+	logger.Info("ok", zap.Namespace("user"), zap.String("password", "secret"))
+	if out := buf.Capture(); !strings.Contains(out, `"request":{"user":{"password":"***"}}`) {
+		t.Fatal(out)
+	}
+
+	// same key outside of namespace is kept
+	logger.Info("ok", zap.String("password", "visible"))
+	if out := buf.Capture(); !strings.Contains(out, `"request":{"password":"visible"}`) {
+		t.Fatal(out)
+	}
+}
+
+func TestRedactPatterns(t *testing.T) {
+	cfg := testRedactConfig("json")
+	cfg.Redact.Replace = "hash"
+
+	logger, buf := testConfigLogger(t, cfg)
+	logger.Info("card 4111 1111 1111 1111 declined",
+		zap.Error(errors.New("invalid header: Bearer abc")),
+		zap.Strings("cards", []string{"4111-1111-1111-1111"}),
+	)
+
+	out := buf.Capture()
+	if strings.Contains(out, "4111") || strings.Contains(out, "abc") {
+		t.Fatal(out)
+	}
+	if strings.Count(out, "sha256:") != 3 || !strings.Contains(out, `"message":"card sha256:`) {
+		t.Fatal(out)
+	}
+}
+
+func TestRedactConfigCheck(t *testing.T) {
+	cfg := testRedactConfig("json")
+	cfg.Redact.Patterns = []string{"("}
+
+	if err := cfg.Check(); err == nil {
+		t.Fatal("expected error of invalid pattern")
+	}
+
+	cfg = testRedactConfig("json")
+	cfg.Redact.Keys = []string{"[token"}
+
+	if err := cfg.Check(); err == nil {
+		t.Fatal("expected error of invalid key")
+	}
+}
@@ -0,0 +1,158 @@
+package zapwriter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rotateSchedule returns next wall-clock boundary after t
+type rotateSchedule interface {
+	Next(t time.Time) time.Time
+}
+
+// parseSchedule accepts "hourly", "daily" or cron-like spec "min hour day month weekday"
+func parseSchedule(spec string) (rotateSchedule, error) {
+	switch strings.ToLower(strings.TrimSpace(spec)) {
+	case "hourly", "@hourly":
+		return hourlySchedule{}, nil
+	case "daily", "@daily", "midnight", "@midnight":
+		return dailySchedule{}, nil
+	}
+	return parseCron(spec)
+}
+
+type hourlySchedule struct{}
+
+func (hourlySchedule) Next(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+}
+
+type dailySchedule struct{}
+
+func (dailySchedule) Next(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+}
+
+type cronSchedule struct {
+	minute  []bool
+	hour    []bool
+	day     []bool
+	month   []bool
+	weekday []bool
+	anyDay  bool // day field is "*"
+	anyWday bool // weekday field is "*"
+}
+
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid rotate schedule %#v: expected 'hourly', 'daily' or 5 cron fields", spec)
+	}
+
+	c := &cronSchedule{
+		anyDay:  fields[2] == "*",
+		anyWday: fields[4] == "*",
+	}
+
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.day, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.weekday, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// 7 is sunday too
+	c.weekday[0] = c.weekday[0] || c.weekday[7]
+
+	return c, nil
+}
+
+// parseCronField parses "*", "*/n", "a-b", "a-b/n" and comma separated lists of them
+func parseCronField(field string, min, max int) ([]bool, error) {
+	res := make([]bool, max+1)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid cron step in %#v", field)
+			}
+			part = part[:i]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			var err error
+			bounds := strings.SplitN(part, "-", 2)
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid cron field %#v", field)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid cron field %#v", field)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("cron field %#v out of range %d-%d", field, min, max)
+		}
+
+		for i := from; i <= to; i += step {
+			res[i] = true
+		}
+	}
+
+	return res, nil
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	day, wday := c.day[t.Day()], c.weekday[int(t.Weekday())]
+	if c.anyDay || c.anyWday {
+		return day && wday
+	}
+	return day || wday
+}
+
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !c.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	// never matches (e.g. "0 0 31 2 *")
+	return time.Time{}
+}
@@ -0,0 +1,45 @@
+package zapwriter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2021, 6, 15, 13, 27, 10, 0, time.UTC) // tuesday
+
+	table := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"hourly", time.Date(2021, 6, 15, 14, 0, 0, 0, time.UTC)},
+		{"daily", time.Date(2021, 6, 16, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, 6, 15, 13, 30, 0, 0, time.UTC)},
+		{"0 */6 * * *", time.Date(2021, 6, 15, 18, 0, 0, 0, time.UTC)},
+		{"30 2 * * 0", time.Date(2021, 6, 20, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 1-3,7 *", time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range table {
+		s, err := parseSchedule(test.spec)
+		if err != nil {
+			t.Fatalf("%#v: %s", test.spec, err)
+		}
+		if next := s.Next(now); !next.Equal(test.expected) {
+			t.Fatalf("%#v: expected %s, got %s", test.spec, test.expected, next)
+		}
+	}
+
+	for _, spec := range []string{"weekly", "* * *", "61 * * * *", "*/0 * * * *"} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Fatalf("%#v: error expected", spec)
+		}
+	}
+}
+
+func TestStrftime(t *testing.T) {
+	tm := time.Date(2021, 6, 5, 3, 4, 5, 6000000, time.UTC)
+	if s := Strftime("logs-%Y.%m.%d %H:%M:%S.%L %% %q", tm); s != "logs-2021.06.05 03:04:05.006 % %q" {
+		t.Fatal(s)
+	}
+}
@@ -0,0 +1,24 @@
+package zapwriter
+
+import "go.uber.org/zap/zapcore"
+
+// SyslogSeverity returns syslog severity (RFC 5424) of zap level. Used by syslog, journald and GELF outputs
+func SyslogSeverity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel:
+		return 2
+	case zapcore.PanicLevel:
+		return 1
+	case zapcore.FatalLevel:
+		return 0
+	}
+	return 6
+}
@@ -0,0 +1,64 @@
+package socket
+
+import (
+	"net/url"
+
+	"github.com/lomik/zapwriter"
+)
+
+func init() {
+	zapwriter.RegisterScheme("tcp", New)
+	zapwriter.RegisterScheme("udp", New)
+	zapwriter.RegisterScheme("unix", New)
+}
+
+// SocketOutput streams newline-delimited messages to socket. Messages are queued in memory
+// while disconnected, connection is restored with exponential backoff.
+//
+// DSN examples:
+//
+//	tcp://127.0.0.1:5170?queue-size=10000&drop-policy=oldest&write-timeout=5s
+//	udp://127.0.0.1:5170
+//	unix:///var/run/vector.sock
+//
+// Queue and reconnect params are described in ParseOptions
+type SocketOutput struct {
+	*Writer
+	newline bool // add newline to messages of stream sockets
+}
+
+func New(path string) (zapwriter.Output, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	address := u.Host
+	if u.Scheme == "unix" {
+		address = u.Path
+	}
+
+	opts, err := ParseOptions(u, u.Scheme, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SocketOutput{
+		Writer:  NewWriter(opts),
+		newline: u.Scheme != "udp",
+	}, nil
+}
+
+func (r *SocketOutput) Write(p []byte) (int, error) {
+	// data race fix
+	m := make([]byte, len(p), len(p)+1)
+	copy(m, p)
+	if r.newline && (len(m) == 0 || m[len(m)-1] != '\n') {
+		m = append(m, '\n')
+	}
+
+	if err := r.Send(m); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
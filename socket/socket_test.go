@@ -0,0 +1,100 @@
+package socket
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSocketReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	out, err := New("tcp://" + ln.Addr().String() + "?backoff-min=10ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.(*SocketOutput).Close()
+
+	out.Write([]byte("first"))
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "first\n" {
+		t.Fatalf("%#v, %v", line, err)
+	}
+	conn.Close()
+
+	// writes to closed connection fail sooner or later, all of them should be delivered after reconnect
+	go func() {
+		for _, m := range []string{"second\n", "third\n"} {
+			out.Write([]byte(m))
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+
+	conn, err = ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line == "third\n" {
+			break
+		}
+	}
+
+	if err := out.Sync(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSocketDropOldest(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	out, err := New("tcp://" + addr + "?queue-size=2&drop-policy=oldest&backoff-min=10ms&sync-timeout=10ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := out.(*SocketOutput)
+	defer s.Close()
+
+	for _, m := range []string{"1\n", "2\n", "3\n", "4\n", "5\n"} {
+		s.Write([]byte(m))
+	}
+
+	// one message can be taken by sender
+	if s.Dropped() < 2 {
+		t.Fatalf("dropped %d", s.Dropped())
+	}
+
+	if last := <-s.queue; string(last[0]) != "4\n" {
+		if last = <-s.queue; string(last[0]) != "4\n" {
+			t.Fatal(string(last[0]))
+		}
+	}
+
+	if err := s.Sync(); err == nil {
+		t.Fatal("sync timeout expected")
+	}
+}
@@ -0,0 +1,248 @@
+package socket
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lomik/zapwriter"
+	"go.uber.org/zap"
+)
+
+// Options of Writer, parsed from DSN by ParseOptions
+type Options struct {
+	Network      string // "tcp", "udp", "unix", "unixgram"
+	Address      string
+	QueueSize    int    // messages waiting for send
+	DropPolicy   string // "oldest", "newest" or "block"
+	WriteTimeout time.Duration
+	BackoffMin   time.Duration
+	BackoffMax   time.Duration
+	SyncTimeout  time.Duration
+	ErrorLogger  string // fallback logger
+}
+
+// ParseOptions parses common options of socket outputs.
+//
+// DSN params:
+//
+//	queue-size=10000          messages waiting for send while disconnected
+//	drop-policy=oldest        what to do with full queue: drop "oldest" or "newest" message, or "block" writes
+//	write-timeout=5s          dial and write timeout
+//	backoff-min=100ms         first reconnect delay, doubled on every failure
+//	backoff-max=30s           max reconnect delay
+//	sync-timeout=5s           max time of Sync waiting for queued messages
+//	error_logger=name         logger for connection errors
+func ParseOptions(u *url.URL, network, address string) (Options, error) {
+	params := zapwriter.DSN(u.Query())
+
+	opts := Options{
+		Network:      network,
+		Address:      address,
+		QueueSize:    10000,
+		DropPolicy:   "oldest",
+		WriteTimeout: 5 * time.Second,
+		BackoffMin:   100 * time.Millisecond,
+		BackoffMax:   30 * time.Second,
+		SyncTimeout:  5 * time.Second,
+	}
+
+	if address == "" {
+		return opts, fmt.Errorf("address is required")
+	}
+
+	if err := zapwriter.AnyError(
+		params.SetInt(&opts.QueueSize, "queue-size"),
+		params.SetString(&opts.DropPolicy, "drop-policy"),
+		params.SetDuration(&opts.WriteTimeout, "write-timeout"),
+		params.SetDuration(&opts.BackoffMin, "backoff-min"),
+		params.SetDuration(&opts.BackoffMax, "backoff-max"),
+		params.SetDuration(&opts.SyncTimeout, "sync-timeout"),
+		params.SetString(&opts.ErrorLogger, "error_logger"),
+	); err != nil {
+		return opts, err
+	}
+
+	if opts.QueueSize < 1 {
+		return opts, fmt.Errorf("queue-size must be positive")
+	}
+
+	if opts.DropPolicy != "oldest" && opts.DropPolicy != "newest" && opts.DropPolicy != "block" {
+		return opts, fmt.Errorf("unknown drop-policy %#v", opts.DropPolicy)
+	}
+
+	return opts, nil
+}
+
+// Writer sends messages to socket from in-memory queue, so writes never wait for network
+// (unless drop-policy is "block"). Connection is restored with exponential backoff.
+// Message is one or more packets written in order, e.g. chunks of datagram protocol
+type Writer struct {
+	opts  Options
+	queue chan [][]byte
+
+	pending int64 // queued and not yet written messages
+	dropped int64
+
+	conn     net.Conn
+	exit     chan interface{}
+	exitOnce sync.Once
+	exitWg   sync.WaitGroup
+}
+
+func NewWriter(opts Options) *Writer {
+	w := &Writer{
+		opts:  opts,
+		queue: make(chan [][]byte, opts.QueueSize),
+		exit:  make(chan interface{}),
+	}
+
+	w.exitWg.Add(1)
+	go func() {
+		w.sender(w.exit)
+		w.exitWg.Done()
+	}()
+
+	return w
+}
+
+// Send queues message. Packets must not be modified after call
+func (w *Writer) Send(packets ...[]byte) error {
+	atomic.AddInt64(&w.pending, 1)
+
+	switch w.opts.DropPolicy {
+	case "block":
+		select {
+		case w.queue <- packets:
+		case <-w.exit:
+			w.drop(1)
+			return fmt.Errorf("aborted")
+		}
+	case "newest":
+		select {
+		case w.queue <- packets:
+		default:
+			w.drop(1)
+			return fmt.Errorf("queue is full")
+		}
+	default: // oldest
+		for {
+			select {
+			case w.queue <- packets:
+				return nil
+			default:
+			}
+
+			select {
+			case <-w.queue:
+				w.drop(1)
+			default:
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) drop(n int64) {
+	atomic.AddInt64(&w.pending, -n)
+	atomic.AddInt64(&w.dropped, n)
+}
+
+// Dropped returns number of messages dropped because of full queue
+func (w *Writer) Dropped() int64 {
+	return atomic.LoadInt64(&w.dropped)
+}
+
+func (w *Writer) logError(msg string, err error) {
+	if w.opts.ErrorLogger != "" {
+		zapwriter.Logger(w.opts.ErrorLogger).Error(msg, zap.String("address", w.opts.Address), zap.Error(err))
+	}
+}
+
+// connect dials until success or exit
+func (w *Writer) connect(exit chan interface{}) bool {
+	backoff := w.opts.BackoffMin
+	for {
+		conn, err := net.DialTimeout(w.opts.Network, w.opts.Address, w.opts.WriteTimeout)
+		if err == nil {
+			w.conn = conn
+			return true
+		}
+
+		w.logError("connect failed", err)
+
+		select {
+		case <-time.After(backoff):
+		case <-exit:
+			return false
+		}
+
+		backoff *= 2
+		if backoff > w.opts.BackoffMax {
+			backoff = w.opts.BackoffMax
+		}
+	}
+}
+
+func (w *Writer) sender(exit chan interface{}) {
+	defer func() {
+		if w.conn != nil {
+			w.conn.Close()
+			w.conn = nil
+		}
+	}()
+
+	for {
+		var packets [][]byte
+		select {
+		case packets = <-w.queue:
+		case <-exit:
+			return
+		}
+
+		// packets written before failure are not repeated
+		for len(packets) > 0 {
+			if w.conn == nil && !w.connect(exit) {
+				w.drop(1)
+				return
+			}
+
+			w.conn.SetWriteDeadline(time.Now().Add(w.opts.WriteTimeout))
+			if _, err := w.conn.Write(packets[0]); err != nil {
+				w.logError("write failed, reconnecting", err)
+				w.conn.Close()
+				w.conn = nil
+				continue
+			}
+
+			packets = packets[1:]
+		}
+
+		atomic.AddInt64(&w.pending, -1)
+	}
+}
+
+// Sync waits until queued messages are written, at most sync-timeout
+func (w *Writer) Sync() error {
+	deadline := time.Now().Add(w.opts.SyncTimeout)
+	for atomic.LoadInt64(&w.pending) > 0 {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("sync timeout, %d messages pending", atomic.LoadInt64(&w.pending))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+func (w *Writer) Close() (err error) {
+	err = w.Sync()
+	w.exitOnce.Do(func() {
+		close(w.exit)
+	})
+	w.exitWg.Wait()
+	return
+}
@@ -0,0 +1,98 @@
+package zapwriter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Strftime formats time with strftime-style pattern. Supported:
+// %Y %y %m %d %H %M %S %L (milliseconds) %j %b %a %F (%Y-%m-%d) %T (%H:%M:%S) %s (unix seconds) %%.
+// Unknown directives are left as is
+func Strftime(pattern string, t time.Time) string {
+	var b strings.Builder
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i == len(pattern)-1 {
+			b.WriteByte(pattern[i])
+			continue
+		}
+
+		i++
+		switch pattern[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'y':
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case 'L':
+			fmt.Fprintf(&b, "%03d", t.Nanosecond()/int(time.Millisecond))
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'b':
+			b.WriteString(t.Format("Jan"))
+		case 'a':
+			b.WriteString(t.Format("Mon"))
+		case 'F':
+			fmt.Fprintf(&b, "%04d-%02d-%02d", t.Year(), int(t.Month()), t.Day())
+		case 'T':
+			fmt.Fprintf(&b, "%02d:%02d:%02d", t.Hour(), t.Minute(), t.Second())
+		case 's':
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(pattern[i])
+		}
+	}
+
+	return b.String()
+}
+
+// strftimeRegexp returns regexp matching results of Strftime(pattern, t) for any t
+func strftimeRegexp(pattern string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i == len(pattern)-1 {
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			continue
+		}
+
+		i++
+		switch pattern[i] {
+		case 'Y':
+			b.WriteString(`\d{4}`)
+		case 'y', 'm', 'd', 'H', 'M', 'S':
+			b.WriteString(`\d{2}`)
+		case 'L', 'j':
+			b.WriteString(`\d{3}`)
+		case 'b', 'a':
+			b.WriteString(`[A-Z][a-z]{2}`)
+		case 'F':
+			b.WriteString(`\d{4}-\d{2}-\d{2}`)
+		case 'T':
+			b.WriteString(`\d{2}:\d{2}:\d{2}`)
+		case 's':
+			b.WriteString(`\d+`)
+		case '%':
+			b.WriteString(`%`)
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i-1 : i+1]))
+		}
+	}
+
+	return b.String()
+}
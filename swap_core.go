@@ -0,0 +1,72 @@
+package zapwriter
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type coreBox struct {
+	core zapcore.Core
+}
+
+// swapCore delegates to core which can be replaced on config reload.
+// Loggers created with With(...) before reload use new core too
+type swapCore struct {
+	src    *atomic.Value // *coreBox, shared by all derived cores
+	fields []zapcore.Field
+	cache  atomic.Value // *swapCache
+}
+
+type swapCache struct {
+	box  *coreBox
+	core zapcore.Core
+}
+
+func newSwapCore(core zapcore.Core) *swapCore {
+	s := &swapCore{src: &atomic.Value{}}
+	s.swap(core)
+	return s
+}
+
+func (s *swapCore) swap(core zapcore.Core) {
+	s.src.Store(&coreBox{core: core})
+}
+
+func (s *swapCore) current() zapcore.Core {
+	box := s.src.Load().(*coreBox)
+	if len(s.fields) == 0 {
+		return box.core
+	}
+
+	if c, ok := s.cache.Load().(*swapCache); ok && c.box == box {
+		return c.core
+	}
+
+	core := box.core.With(s.fields)
+	s.cache.Store(&swapCache{box: box, core: core})
+	return core
+}
+
+func (s *swapCore) Enabled(lvl zapcore.Level) bool {
+	return s.current().Enabled(lvl)
+}
+
+func (s *swapCore) With(fields []zapcore.Field) zapcore.Core {
+	f := make([]zapcore.Field, 0, len(s.fields)+len(fields))
+	f = append(f, s.fields...)
+	f = append(f, fields...)
+	return &swapCore{src: s.src, fields: f}
+}
+
+func (s *swapCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return s.current().Check(ent, ce)
+}
+
+func (s *swapCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return s.current().Write(ent, fields)
+}
+
+func (s *swapCore) Sync() error {
+	return s.current().Sync()
+}
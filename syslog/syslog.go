@@ -0,0 +1,188 @@
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lomik/zapwriter"
+	"github.com/lomik/zapwriter/socket"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	zapwriter.RegisterScheme("syslog", New)
+}
+
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// SyslogOutput sends messages to syslog.
+//
+// DSN examples:
+//
+//	syslog:///dev/log                            local socket (unixgram)
+//	syslog://127.0.0.1:514?transport=udp
+//	syslog://127.0.0.1:514?transport=tcp&format=rfc5424&facility=local0&app-name=app
+//
+// Messages are queued and sent in background, connection is restored with backoff.
+// Queue and reconnect params are described in socket.ParseOptions, timeout is alias of write-timeout
+type SyslogOutput struct {
+	*socket.Writer
+	network  string
+	format   string // "rfc5424" or "rfc3164"
+	octet    bool   // octet counting framing for stream transports
+	facility int
+	hostname string
+	appName  string
+	pid      int
+}
+
+func New(path string) (zapwriter.Output, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	params := zapwriter.DSN(u.Query())
+
+	network, address := "unixgram", u.Path
+	if u.Host != "" {
+		network, address = "udp", u.Host
+	}
+	if address == "" {
+		address = "/dev/log"
+	}
+
+	transport, err := params.String("transport", network)
+	if err != nil {
+		return nil, err
+	}
+	switch transport {
+	case "udp", "tcp", "unixgram", "unix":
+	default:
+		return nil, fmt.Errorf("unknown transport %#v", transport)
+	}
+
+	format, err := params.String("format", "rfc5424")
+	if err != nil {
+		return nil, err
+	}
+	if format != "rfc5424" && format != "rfc3164" {
+		return nil, fmt.Errorf("unknown format %#v", format)
+	}
+
+	framing, err := params.String("framing", map[string]string{"rfc5424": "octet", "rfc3164": "newline"}[format])
+	if err != nil {
+		return nil, err
+	}
+	if framing != "octet" && framing != "newline" {
+		return nil, fmt.Errorf("unknown framing %#v", framing)
+	}
+
+	facilityName, err := params.String("facility", "user")
+	if err != nil {
+		return nil, err
+	}
+	facility, ok := facilities[facilityName]
+	if !ok {
+		return nil, fmt.Errorf("unknown facility %#v", facilityName)
+	}
+
+	appName, err := params.String("app-name", filepath.Base(os.Args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := socket.ParseOptions(u, transport, address)
+	if err != nil {
+		return nil, err
+	}
+	if err := params.SetDuration(&opts.WriteTimeout, "timeout"); err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+
+	return &SyslogOutput{
+		Writer:   socket.NewWriter(opts),
+		network:  transport,
+		format:   format,
+		octet:    framing == "octet",
+		facility: facility,
+		hostname: hostname,
+		appName:  appName,
+		pid:      os.Getpid(),
+	}, nil
+}
+
+func (s *SyslogOutput) stream() bool {
+	return s.network == "tcp" || s.network == "unix"
+}
+
+// message returns syslog message with header. p is encoded message without trailing newline
+func (s *SyslogOutput) message(ent zapcore.Entry, p []byte) []byte {
+	pri := s.facility*8 + zapwriter.SyslogSeverity(ent.Level)
+
+	var header string
+	if s.format == "rfc3164" {
+		header = fmt.Sprintf("<%d>%s %s %s[%d]: ",
+			pri, ent.Time.Format(time.Stamp), s.hostname, s.appName, s.pid)
+	} else {
+		msgID := ent.LoggerName
+		if msgID == "" {
+			msgID = "-"
+		}
+		header = fmt.Sprintf("<%d>1 %s %s %s %d %s - ",
+			pri, ent.Time.Format(time.RFC3339Nano), s.hostname, s.appName, s.pid, msgID)
+	}
+
+	msg := make([]byte, 0, len(header)+len(p)+16)
+	if s.stream() && s.octet {
+		msg = append(msg, fmt.Sprintf("%d ", len(header)+len(p))...)
+	}
+	msg = append(msg, header...)
+	msg = append(msg, p...)
+	if s.stream() && !s.octet {
+		msg = append(msg, '\n')
+	}
+	return msg
+}
+
+func (s *SyslogOutput) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) (int, error) {
+	if err := s.Send(s.message(ent, bytes.TrimRight(p, "\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Write sends message with info severity if entry is unknown
+func (s *SyslogOutput) Write(p []byte) (int, error) {
+	return s.WriteEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now()}, nil, p)
+}
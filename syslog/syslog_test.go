@@ -0,0 +1,53 @@
+package syslog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/lomik/zapwriter"
+)
+
+func TestSyslogUnixgram(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sock := filepath.Join(dir, "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	cfg := zapwriter.NewConfig()
+	cfg.File = fmt.Sprintf("syslog://%s?facility=local3&app-name=test", sock)
+	cfg.Encoding = "json"
+
+	logger, err := cfg.BuildLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Named("access").Warn("message text")
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// local3 * 8 + warning
+	expected := regexp.MustCompile(fmt.Sprintf(`^<156>1 \S+ \S+ test %d access - \{.*"message":"message text"\}$`, os.Getpid()))
+	if !expected.Match(buf[:n]) {
+		t.Fatal(string(buf[:n]))
+	}
+}
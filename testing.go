@@ -62,11 +62,7 @@ func testWithConfig(cfg Config) func() {
 		),
 	)
 
-	m := &manager{
-		writers: make(map[string]WriteSyncer),
-		cores:   make(map[string][]zapcore.Core),
-		loggers: make(map[string]*zap.Logger),
-	}
+	m := newManager()
 
 	m.loggers[""] = logger
 